@@ -1,42 +1,209 @@
 package api
 
 import (
+	"context"
 	"database/sql"
-	"log"
+	"errors"
+	"log/slog"
+	"net"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
+	"github.com/Jay1570/learning-go/config"
+	"github.com/Jay1570/learning-go/db"
+	"github.com/Jay1570/learning-go/services/auth"
 	"github.com/Jay1570/learning-go/services/logging"
+	"github.com/Jay1570/learning-go/services/mail"
+	"github.com/Jay1570/learning-go/services/middleware"
+	"github.com/Jay1570/learning-go/services/passwordreset"
 	"github.com/Jay1570/learning-go/services/product"
+	"github.com/Jay1570/learning-go/services/token"
 	"github.com/Jay1570/learning-go/services/user"
+	"github.com/Jay1570/learning-go/services/verification"
+	"github.com/Jay1570/learning-go/types"
+	"github.com/Jay1570/learning-go/utils"
 )
 
+const shutdownTimeout = 10 * time.Second
+
 type APIServer struct {
-	addr string
-	db   *sql.DB
+	addr      string
+	db        *sql.DB
+	startedAt time.Time
 }
 
 func NewAPIServer(addr string, db *sql.DB) *APIServer {
 	return &APIServer{
-		addr: addr,
-		db:   db,
+		addr:      addr,
+		db:        db,
+		startedAt: time.Now(),
 	}
 }
 
 func (s *APIServer) Run() error {
-	router := http.NewServeMux()
-	subrouter := http.NewServeMux()
-
 	userStore := user.NewStore(s.db)
-	userHandler := user.NewHandler(userStore)
-	userHandler.RegisterRoutes(subrouter)
+	tokenStore := token.NewStore(s.db)
+	verificationStore := verification.NewStore(s.db)
+	resetStore := passwordreset.NewStore(s.db)
+	mailer, err := mail.NewSender()
+	if err != nil {
+		return err
+	}
+	userHandler := user.NewHandler(userStore, tokenStore, verificationStore, resetStore, mailer)
 
 	productStore := product.NewStore(s.db)
+	if ttl := config.Envs.ProductCacheTTLInSeconds; ttl > 0 {
+		productStore.WithCache(db.NewReadCache(db.NewLRUCache(int(config.Envs.ProductCacheSize)), time.Duration(ttl)*time.Second))
+	}
 	productHandler := product.NewHandler(productStore, userStore)
-	productHandler.RegisterRoutes(subrouter)
 
-	router.Handle("/api/", http.StripPrefix("/api/v1", subrouter))
+	router := s.routes(userHandler, productHandler, userStore)
+
+	cors, err := middleware.CORS(router, middleware.CORSOptions{
+		AllowedOrigins: config.Envs.CORSAllowedOrigins,
+		AllowedMethods: config.Envs.CORSAllowedMethods,
+		AllowedHeaders: config.Envs.CORSAllowedHeaders,
+	}, credentialedCORSGroups()...)
+	if err != nil {
+		return err
+	}
+
+	compressed := middleware.Gzip(cors, int(config.Envs.GzipMinSizeBytes))
+
+	server := &http.Server{
+		Addr:         s.addr,
+		Handler:      middleware.Recovery(logging.Logging(compressed)),
+		ReadTimeout:  15 * time.Second,
+		WriteTimeout: 15 * time.Second,
+		IdleTimeout:  60 * time.Second,
+	}
+
+	useTLS := config.Envs.TLSCertFile != "" && config.Envs.TLSKeyFile != ""
+
+	serverErr := make(chan error, 2)
+	go func() {
+		if useTLS {
+			slog.Info("listening", "addr", s.addr, "tls", true)
+			serverErr <- server.ListenAndServeTLS(config.Envs.TLSCertFile, config.Envs.TLSKeyFile)
+			return
+		}
+
+		slog.Info("listening", "addr", s.addr, "tls", false)
+		serverErr <- server.ListenAndServe()
+	}()
+
+	// Plain HTTP clients get redirected to the TLS listener
+	var redirectServer *http.Server
+	if useTLS && config.Envs.HTTPRedirectAddr != "" {
+		redirectServer = &http.Server{
+			Addr:         config.Envs.HTTPRedirectAddr,
+			Handler:      http.HandlerFunc(s.redirectToHTTPS),
+			ReadTimeout:  5 * time.Second,
+			WriteTimeout: 5 * time.Second,
+		}
+
+		go func() {
+			slog.Info("redirecting http to https", "addr", config.Envs.HTTPRedirectAddr)
+			serverErr <- redirectServer.ListenAndServe()
+		}()
+	}
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(stop)
+
+	select {
+	case err := <-serverErr:
+		if errors.Is(err, http.ErrServerClosed) {
+			return nil
+		}
+		return err
+	case sig := <-stop:
+		slog.Info("shutting down", "signal", sig.String())
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	if redirectServer != nil {
+		if err := redirectServer.Shutdown(ctx); err != nil {
+			slog.Error("failed to shut down the redirect listener", "error", err)
+		}
+	}
+
+	return server.Shutdown(ctx)
+}
 
-	log.Println("Listening on", s.addr)
+// routes mounts the service handlers of every API version next to the
+// health checks. A v2 gets its own mountVersion call with its own handlers,
+// so v1 clients keep working while it evolves.
+func (s *APIServer) routes(userHandler *user.Handler, productHandler *product.Handler, userStore types.UserStore) *http.ServeMux {
+	router := http.NewServeMux()
+
+	router.HandleFunc("GET /health", s.handleHealth)
+	router.HandleFunc("GET /ready", s.handleReady)
+
+	// The debug info is opt-in and even then only for admins
+	if config.Envs.DebugInfoEnabled {
+		adminOnly := auth.RequireRole(types.RoleAdmin)
+		router.Handle("GET /debug/info", auth.WithJWTAuth(adminOnly(http.HandlerFunc(s.handleDebugInfo)), userStore))
+	}
+
+	mountVersion(router, "v1", userHandler.RegisterRoutes, productHandler.RegisterRoutes)
+
+	return router
+}
+
+// mountVersion registers a set of handlers on a subrouter served under
+// "<API_BASE_PATH>/<version>". The mount pattern and the stripped prefix have
+// to agree, otherwise the subrouter sees paths it has no routes for.
+func mountVersion(router *http.ServeMux, version string, registrars ...func(*http.ServeMux)) {
+	subrouter := http.NewServeMux()
+	for _, register := range registrars {
+		register(subrouter)
+	}
+
+	prefix := utils.VersionPrefix(version)
+	router.Handle(prefix+"/", http.StripPrefix(prefix, subrouter))
+}
+
+// credentialedCORSGroups lets the configured origins send cookies to the
+// configured v1 paths, which the cookie auth mode needs. Every other route
+// keeps the global, credential-less options.
+func credentialedCORSGroups() []middleware.CORSGroup {
+	if len(config.Envs.CORSCredentialsOrigins) == 0 {
+		return nil
+	}
+
+	options := middleware.CORSOptions{
+		AllowedOrigins:   config.Envs.CORSCredentialsOrigins,
+		AllowedMethods:   config.Envs.CORSAllowedMethods,
+		AllowedHeaders:   config.Envs.CORSAllowedHeaders,
+		AllowCredentials: true,
+	}
+
+	groups := make([]middleware.CORSGroup, len(config.Envs.CORSCredentialsPaths))
+	for i, path := range config.Envs.CORSCredentialsPaths {
+		groups[i] = middleware.CORSGroup{PathPrefix: utils.VersionPrefix("v1") + path, Options: options}
+	}
+
+	return groups
+}
+
+// redirectToHTTPS permanently redirects a request to the same URL over HTTPS
+// on the API's own port
+func (s *APIServer) redirectToHTTPS(w http.ResponseWriter, r *http.Request) {
+	host, _, err := net.SplitHostPort(r.Host)
+	if err != nil {
+		host = r.Host
+	}
+
+	if _, port, err := net.SplitHostPort(s.addr); err == nil && port != "443" {
+		host = net.JoinHostPort(host, port)
+	}
 
-	return http.ListenAndServe(s.addr, logging.Logging(router))
+	http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusPermanentRedirect)
 }
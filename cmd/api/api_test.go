@@ -0,0 +1,143 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Jay1570/learning-go/config"
+	"github.com/Jay1570/learning-go/services/auth"
+	"github.com/Jay1570/learning-go/services/middleware"
+	"github.com/Jay1570/learning-go/services/product"
+	"github.com/Jay1570/learning-go/services/user"
+	"github.com/Jay1570/learning-go/testutil"
+	"github.com/Jay1570/learning-go/types"
+	"github.com/Jay1570/learning-go/utils"
+)
+
+func TestRoutes(t *testing.T) {
+	config.Envs.JWTSecret = "test-secret"
+
+	userStore := testutil.NewUserStore(types.User{Email: "user@mail.com", Password: "not-a-hash"})
+	productStore := testutil.NewProductStore(types.Product{Name: "chair", Price: 1000, Quantity: 3})
+
+	s := NewAPIServer(":0", nil)
+	router := s.routes(
+		user.NewHandler(userStore, nil, nil, nil, nil),
+		product.NewHandler(productStore, userStore),
+		userStore,
+	)
+
+	t.Run("should route /api/v1/login to the user handler", func(t *testing.T) {
+		marshalled, _ := json.Marshal(types.LoginUserPayload{Email: "user@mail.com", Password: "wrong-password"})
+		req, err := http.NewRequest(http.MethodPost, "/api/v1/login", bytes.NewBuffer(marshalled))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("expexted status code %d, got %d", http.StatusBadRequest, rr.Code)
+		}
+	})
+
+	t.Run("should route /api/v1/products to the product handler", func(t *testing.T) {
+		token, err := auth.CreateJWT(config.Envs.JWTSecret, 1, types.RoleUser)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req, err := http.NewRequest(http.MethodGet, "/api/v1/products", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", token)
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		if rr.Code != http.StatusOK {
+			t.Errorf("expexted status code %d, got %d", http.StatusOK, rr.Code)
+		}
+	})
+
+	t.Run("should serve several versions side by side", func(t *testing.T) {
+		router := http.NewServeMux()
+		for _, version := range []string{"v1", "v2"} {
+			mountVersion(router, version, func(mux *http.ServeMux) {
+				mux.HandleFunc("GET /version", func(w http.ResponseWriter, r *http.Request) {
+					w.Write([]byte(version))
+				})
+			})
+		}
+
+		for _, version := range []string{"v1", "v2"} {
+			req, err := http.NewRequest(http.MethodGet, "/api/"+version+"/version", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			if rr.Body.String() != version {
+				t.Errorf("expected %s to answer, got %q", version, rr.Body.String())
+			}
+		}
+	})
+
+	t.Run("should mount under the configured base path", func(t *testing.T) {
+		basePath := config.Envs.APIBasePath
+		config.Envs.APIBasePath = "/store"
+		defer func() { config.Envs.APIBasePath = basePath }()
+
+		if prefix := utils.VersionPrefix("v1"); prefix != "/store/v1" {
+			t.Errorf("expected /store/v1, got %q", prefix)
+		}
+	})
+
+	t.Run("should let credentialed origins send cookies to the cookie auth routes", func(t *testing.T) {
+		origins := config.Envs.CORSCredentialsOrigins
+		config.Envs.CORSCredentialsOrigins = []string{"https://shop.example.com"}
+		defer func() { config.Envs.CORSCredentialsOrigins = origins }()
+
+		handler, err := middleware.CORS(router, middleware.CORSOptions{AllowedOrigins: []string{"*"}}, credentialedCORSGroups()...)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, path := range []string{"/api/v1/login", "/api/v1/products", "/api/v1/products/1", "/api/v1/users"} {
+			req, err := http.NewRequest(http.MethodOptions, path, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Set("Origin", "https://shop.example.com")
+			req.Header.Set("Access-Control-Request-Method", http.MethodGet)
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			if rr.Header().Get("Access-Control-Allow-Credentials") != "true" {
+				t.Errorf("%s: expected credentials to be allowed", path)
+			}
+		}
+	})
+
+	t.Run("should not route paths outside /api/v1", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodPost, "/api/login", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		if rr.Code != http.StatusNotFound {
+			t.Errorf("expexted status code %d, got %d", http.StatusNotFound, rr.Code)
+		}
+	})
+}
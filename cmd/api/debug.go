@@ -0,0 +1,104 @@
+package api
+
+import (
+	"net/http"
+	"reflect"
+	"runtime"
+	"runtime/debug"
+	"slices"
+	"time"
+
+	"github.com/Jay1570/learning-go/config"
+	"github.com/Jay1570/learning-go/utils"
+)
+
+// Version and Commit describe the running build. They can be set at link
+// time (-ldflags "-X github.com/Jay1570/learning-go/cmd/api.Version=1.2.0"),
+// otherwise they are read from the build info Go embeds in the binary.
+var (
+	Version = ""
+	Commit  = ""
+)
+
+// publicConfigFields are the config fields whose values the debug info may
+// show. Every other field is redacted, so a new one stays private until it is
+// added here.
+var publicConfigFields = []string{
+	"Environment", "PublicHost", "Port", "APIBasePath",
+	"JWTExpirationInSeconds", "JWTAlgorithm", "JWTIssuer", "JWTAudience",
+	"AuthCookieEnabled", "AuthCookieSecure",
+	"RefreshTokenExpirationInSeconds", "VerificationTokenExpirationInSeconds",
+	"PasswordResetTokenExpirationInSeconds", "RequireEmailVerification", "PasswordResetURL",
+	"DBMaxOpenConns", "DBMaxIdleConns", "DBConnMaxLifetimeInSeconds", "DBConnMaxIdleTimeInSeconds",
+	"DBSlowQueryThresholdInMs",
+	"AuthRateLimit", "AuthRateLimitWindowInSeconds",
+	"PasswordMinLength", "PasswordRequiredClasses", "BcryptCost",
+	"MaxRequestBodyBytes", "DisallowUnknownJSONFields",
+	"IdempotencyKeyTTLInSeconds", "GzipMinSizeBytes",
+	"ProductCacheTTLInSeconds", "ProductCacheSize",
+	"LogLevel", "DebugInfoEnabled", "RunMigrations", "SeedData",
+	"CORSAllowedOrigins", "CORSAllowedMethods", "CORSAllowedHeaders",
+	"CORSCredentialsOrigins", "CORSCredentialsPaths",
+}
+
+func (s *APIServer) handleDebugInfo(w http.ResponseWriter, r *http.Request) {
+	version, commit := buildVersion()
+
+	response := map[string]any{
+		"status":    http.StatusOK,
+		"version":   version,
+		"commit":    commit,
+		"goVersion": runtime.Version(),
+		"startedAt": s.startedAt,
+		"uptime":    time.Since(s.startedAt).Round(time.Second).String(),
+		"config":    redactedConfig(config.Envs),
+	}
+	utils.WriteJSON(w, response["status"].(int), response)
+}
+
+// buildVersion prefers the link-time values and falls back to the module
+// version and VCS revision recorded by the go command
+func buildVersion() (string, string) {
+	version, commit := Version, Commit
+
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return version, commit
+	}
+
+	if version == "" {
+		version = info.Main.Version
+	}
+
+	if commit == "" {
+		for _, setting := range info.Settings {
+			if setting.Key == "vcs.revision" {
+				commit = setting.Value
+			}
+		}
+	}
+
+	return version, commit
+}
+
+// redactedConfig lists every config field, masking the ones outside
+// publicConfigFields. A masked field that isn't set shows as empty so a
+// missing one is still visible.
+func redactedConfig(cfg config.Config) map[string]any {
+	v := reflect.ValueOf(cfg)
+	t := v.Type()
+
+	redacted := make(map[string]any, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		name := t.Field(i).Name
+		value := v.Field(i).Interface()
+
+		if !slices.Contains(publicConfigFields, name) && !v.Field(i).IsZero() {
+			value = "[redacted]"
+		}
+
+		redacted[name] = value
+	}
+
+	return redacted
+}
@@ -0,0 +1,87 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Jay1570/learning-go/config"
+	"github.com/Jay1570/learning-go/services/auth"
+	"github.com/Jay1570/learning-go/services/product"
+	"github.com/Jay1570/learning-go/services/user"
+	"github.com/Jay1570/learning-go/testutil"
+	"github.com/Jay1570/learning-go/types"
+)
+
+func TestDebugInfo(t *testing.T) {
+	config.Envs.JWTSecret = "test-secret"
+	config.Envs.DebugInfoEnabled = true
+	defer func() { config.Envs.DebugInfoEnabled = false }()
+	defer func(address string) { config.Envs.DBAddress = address }(config.Envs.DBAddress)
+	config.Envs.DBAddress = "db.internal:3306"
+
+	// The role comes from the stored user, whatever the token claims
+	userStore := testutil.NewUserStore(
+		types.User{ID: 1, Email: "admin@mail.com", Role: types.RoleAdmin},
+		types.User{ID: 2, Email: "user@mail.com", Role: types.RoleUser},
+	)
+	s := NewAPIServer(":0", nil)
+	router := s.routes(
+		user.NewHandler(userStore, nil, nil, nil, nil),
+		product.NewHandler(testutil.NewProductStore(), userStore),
+		userStore,
+	)
+
+	send := func(userID int, role string) *httptest.ResponseRecorder {
+		token, err := auth.CreateJWT(config.Envs.JWTSecret, userID, role)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req, err := http.NewRequest(http.MethodGet, "/debug/info", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", token)
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	t.Run("should show the build and a redacted config to admins", func(t *testing.T) {
+		rr := send(1, types.RoleAdmin)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expexted status code %d, got %d", http.StatusOK, rr.Code)
+		}
+
+		var body struct {
+			GoVersion string         `json:"goVersion"`
+			Config    map[string]any `json:"config"`
+		}
+		if err := json.NewDecoder(rr.Body).Decode(&body); err != nil {
+			t.Fatal(err)
+		}
+
+		if body.GoVersion == "" {
+			t.Error("expected the Go version")
+		}
+		if body.Config["JWTSecret"] != "[redacted]" {
+			t.Errorf("expected the JWT secret to be redacted, got %v", body.Config["JWTSecret"])
+		}
+		if body.Config["DBAddress"] != "[redacted]" {
+			t.Errorf("expected a field outside the allowlist to be redacted, got %v", body.Config["DBAddress"])
+		}
+		if body.Config["Port"] != config.Envs.Port {
+			t.Errorf("expected the port %q, got %v", config.Envs.Port, body.Config["Port"])
+		}
+	})
+
+	t.Run("should refuse other users", func(t *testing.T) {
+		rr := send(2, types.RoleUser)
+		if rr.Code != http.StatusForbidden {
+			t.Errorf("expexted status code %d, got %d", http.StatusForbidden, rr.Code)
+		}
+	})
+}
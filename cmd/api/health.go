@@ -0,0 +1,34 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/Jay1570/learning-go/utils"
+)
+
+const readinessTimeout = 2 * time.Second
+
+func (s *APIServer) handleHealth(w http.ResponseWriter, r *http.Request) {
+	utils.WriteJSON(w, http.StatusOK, map[string]any{
+		"status": "ok",
+	})
+}
+
+func (s *APIServer) handleReady(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), readinessTimeout)
+	defer cancel()
+
+	if err := s.db.PingContext(ctx); err != nil {
+		utils.WriteJSON(w, http.StatusServiceUnavailable, map[string]any{
+			"status": "unavailable",
+			"error":  "database unreachable",
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, map[string]any{
+		"status": "ready",
+	})
+}
@@ -2,16 +2,23 @@ package main
 
 import (
 	"database/sql"
-	"log"
+	"log/slog"
+	"os"
+	"time"
 
 	"github.com/Jay1570/learning-go/cmd/api"
+	"github.com/Jay1570/learning-go/cmd/migrate/migrations"
 	"github.com/Jay1570/learning-go/config"
 	"github.com/Jay1570/learning-go/db"
+	"github.com/Jay1570/learning-go/services/logging"
+	"github.com/Jay1570/learning-go/services/seed"
 	"github.com/go-sql-driver/mysql"
 )
 
 func main() {
-	db, err := db.NewMySqlStorage(mysql.Config{
+	slog.SetDefault(logging.NewLogger(os.Stdout, config.Envs.LogLevel))
+
+	database, err := db.NewMySqlStorage(mysql.Config{
 		User:                 config.Envs.DBUser,
 		Passwd:               config.Envs.DBPassword,
 		Addr:                 config.Envs.DBAddress,
@@ -21,22 +28,50 @@ func main() {
 		ParseTime:            true,
 	})
 	if err != nil {
-		log.Fatal(err)
+		slog.Error("failed to open the database", "error", err)
+		os.Exit(1)
+	}
+
+	db.ConfigurePool(database, db.PoolConfig{
+		MaxOpenConns:    int(config.Envs.DBMaxOpenConns),
+		MaxIdleConns:    int(config.Envs.DBMaxIdleConns),
+		ConnMaxLifetime: time.Duration(config.Envs.DBConnMaxLifetimeInSeconds) * time.Second,
+		ConnMaxIdleTime: time.Duration(config.Envs.DBConnMaxIdleTimeInSeconds) * time.Second,
+	})
+
+	db.SlowQueryThreshold = time.Duration(config.Envs.DBSlowQueryThresholdInMs) * time.Millisecond
+
+	initStorage(database)
+
+	if config.Envs.RunMigrations {
+		if err := db.Migrate(database, migrations.FS); err != nil {
+			slog.Error("failed to run migrations", "error", err)
+			os.Exit(1)
+		}
+		slog.Info("migrations applied")
 	}
 
-	initStorage(db)
+	if config.Envs.SeedData {
+		if err := seed.Seed(database, seed.Default()); err != nil {
+			slog.Error("failed to seed the database", "error", err)
+			os.Exit(1)
+		}
+		slog.Info("sample data seeded")
+	}
 
-	server := api.NewAPIServer(":5000", db)
+	server := api.NewAPIServer(":5000", database)
 	if err := server.Run(); err != nil {
-		log.Fatal(err)
+		slog.Error("server stopped", "error", err)
+		os.Exit(1)
 	}
 }
 
 func initStorage(db *sql.DB) {
 	err := db.Ping()
 	if err != nil {
-		log.Fatal(err)
+		slog.Error("failed to connect to the database", "error", err)
+		os.Exit(1)
 	}
 
-	log.Println("DB: Successfully connected!")
+	slog.Info("database connected")
 }
@@ -1,11 +1,12 @@
 package main
 
 import (
-	"log"
+	"log/slog"
 	"os"
 
 	"github.com/Jay1570/learning-go/config"
 	"github.com/Jay1570/learning-go/db"
+	"github.com/Jay1570/learning-go/services/logging"
 	mysqlDriver "github.com/go-sql-driver/mysql"
 	"github.com/golang-migrate/migrate/v4"
 	mysqlMigrate "github.com/golang-migrate/migrate/v4/database/mysql"
@@ -13,6 +14,8 @@ import (
 )
 
 func main() {
+	slog.SetDefault(logging.NewLogger(os.Stdout, config.Envs.LogLevel))
+
 	db, err := db.NewMySqlStorage(mysqlDriver.Config{
 		User:                 config.Envs.DBUser,
 		Passwd:               config.Envs.DBPassword,
@@ -23,12 +26,12 @@ func main() {
 		ParseTime:            true,
 	})
 	if err != nil {
-		log.Fatal(err)
+		fatal(err)
 	}
 
 	driver, err := mysqlMigrate.WithInstance(db, &mysqlMigrate.Config{})
 	if err != nil {
-		log.Fatal(err)
+		fatal(err)
 	}
 
 	m, err := migrate.NewWithDatabaseInstance(
@@ -37,20 +40,25 @@ func main() {
 		driver,
 	)
 	if err != nil {
-		log.Fatal(err)
+		fatal(err)
 	}
 
 	cmd := os.Args[(len(os.Args) - 1)]
 	if cmd == "up" {
 		if err := m.Up(); err != nil && err != migrate.ErrNoChange {
-			log.Fatal(err)
+			fatal(err)
 		}
 	}
 	if cmd == "down" {
 		if err := m.Down(); err != nil && err != migrate.ErrNoChange {
-			log.Fatal(err)
+			fatal(err)
 		}
 	}
 
 	m.Up()
 }
+
+func fatal(err error) {
+	slog.Error("migration failed", "error", err)
+	os.Exit(1)
+}
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+  `id` INT UNSIGNED NOT NULL AUTO_INCREMENT,
+  `userId` INT UNSIGNED NOT NULL,
+  `tokenHash` CHAR(64) NOT NULL,
+  `expiresAt` TIMESTAMP NOT NULL,
+  `revokedAt` TIMESTAMP NULL DEFAULT NULL,
+  `createdAt` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+  PRIMARY KEY (`id`),
+  UNIQUE KEY (`tokenHash`),
+  FOREIGN KEY (`userId`) REFERENCES users(`id`) ON DELETE CASCADE
+);
\ No newline at end of file
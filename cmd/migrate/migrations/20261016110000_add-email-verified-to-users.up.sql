@@ -0,0 +1 @@
+ALTER TABLE users ADD COLUMN `emailVerified` BOOLEAN NOT NULL DEFAULT FALSE AFTER `role`;
@@ -0,0 +1 @@
+ALTER TABLE products ADD COLUMN `createdBy` INT UNSIGNED NULL DEFAULT NULL, ADD CONSTRAINT `fk_products_created_by` FOREIGN KEY (`createdBy`) REFERENCES users(`id`) ON DELETE SET NULL;
@@ -0,0 +1 @@
+ALTER TABLE products ADD COLUMN `categoryId` INT UNSIGNED NULL DEFAULT NULL, ADD CONSTRAINT `fk_products_category` FOREIGN KEY (`categoryId`) REFERENCES categories(`id`) ON DELETE SET NULL;
@@ -0,0 +1,2 @@
+-- Renamed duplicates keep their new names
+SELECT 1;
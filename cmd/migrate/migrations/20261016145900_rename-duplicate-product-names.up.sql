@@ -0,0 +1,3 @@
+-- The unique key on products.name needs unique names first: every duplicate
+-- but the oldest gets its id appended
+UPDATE products p JOIN (SELECT `name`, MIN(`id`) AS `keepId` FROM products GROUP BY `name` HAVING COUNT(*) > 1) d ON d.`name` = p.`name` AND p.`id` <> d.`keepId` SET p.`name` = CONCAT(p.`name`, ' #', p.`id`);
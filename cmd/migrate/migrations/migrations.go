@@ -0,0 +1,8 @@
+// Package migrations embeds the SQL migrations so the API can apply them
+// itself on startup (see config.Envs.RunMigrations).
+package migrations
+
+import "embed"
+
+//go:embed *.sql
+var FS embed.FS
@@ -4,19 +4,92 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
 
 	"github.com/joho/godotenv"
 )
 
 type Config struct {
+	// Environment is empty unless APP_ENV is set, so nothing mistakes an
+	// unconfigured deploy for development
+	Environment            string
 	PublicHost             string
 	Port                   string
+	APIBasePath            string
 	DBUser                 string
 	DBPassword             string
 	DBAddress              string
 	DBName                 string
 	JWTSecret              string
 	JWTExpirationInSeconds int64
+	JWTAlgorithm           string
+	JWTPrivateKeyPath      string
+	JWTPublicKeyPath       string
+	JWTIssuer              string
+	JWTAudience            string
+
+	AuthCookieEnabled bool
+	AuthCookieSecure  bool
+
+	RefreshTokenExpirationInSeconds       int64
+	VerificationTokenExpirationInSeconds  int64
+	PasswordResetTokenExpirationInSeconds int64
+	RequireEmailVerification              bool
+
+	// PasswordResetURL is the frontend page the reset email links to; it
+	// posts the token and the new password to the API
+	PasswordResetURL string
+
+	DBMaxOpenConns             int64
+	DBMaxIdleConns             int64
+	DBConnMaxLifetimeInSeconds int64
+	DBConnMaxIdleTimeInSeconds int64
+	DBSlowQueryThresholdInMs   int64
+
+	AuthRateLimit                int64
+	AuthRateLimitWindowInSeconds int64
+
+	PasswordMinLength       int64
+	PasswordRequiredClasses int64
+	BcryptCost              int64
+
+	TLSCertFile      string
+	TLSKeyFile       string
+	HTTPRedirectAddr string
+
+	MaxRequestBodyBytes       int64
+	DisallowUnknownJSONFields bool
+
+	IdempotencyKeyTTLInSeconds int64
+
+	GzipMinSizeBytes int64
+
+	ProductCacheTTLInSeconds int64
+	ProductCacheSize         int64
+
+	LogLevel string
+
+	// Emails go through SMTP when SMTPHost is set; only development may fall
+	// back to logging them
+	SMTPHost     string
+	SMTPPort     string
+	SMTPUsername string
+	SMTPPassword string
+	SMTPFrom     string
+
+	DebugInfoEnabled bool
+
+	RunMigrations bool
+	SeedData      bool
+
+	CORSAllowedOrigins []string
+	CORSAllowedMethods []string
+	CORSAllowedHeaders []string
+
+	// Credentialed CORS is off unless origins are listed. The paths are every
+	// v1 route that reads the auth cookie.
+	CORSCredentialsOrigins []string
+	CORSCredentialsPaths   []string
 }
 
 var Envs = initConfig()
@@ -24,14 +97,79 @@ var Envs = initConfig()
 func initConfig() Config {
 	godotenv.Load()
 	return Config{
+		Environment:            getEnv("APP_ENV", ""),
 		PublicHost:             getEnv("PUBLIC_HOST", "http://localhost"),
 		Port:                   getEnv("PORT", "5000"),
+		APIBasePath:            normalizePath(getEnv("API_BASE_PATH", "/api")),
 		DBUser:                 getEnv("DB_USER", "root"),
 		DBPassword:             getEnv("DB_PASSWORD", ""),
 		DBAddress:              fmt.Sprintf("%s:%s", getEnv("DB_HOST", "127.0.0.1"), getEnv("DB_PORT", "3306")),
 		DBName:                 getEnv("DB_NAME", ""),
 		JWTSecret:              getEnv("JWT_SECRET", ""),
 		JWTExpirationInSeconds: getEnvAsInt("JWT_EXPIRY", 3600*24*7),
+		JWTAlgorithm:           getEnv("JWT_ALGORITHM", "HS256"),
+		JWTPrivateKeyPath:      getEnv("JWT_PRIVATE_KEY_PATH", ""),
+		JWTPublicKeyPath:       getEnv("JWT_PUBLIC_KEY_PATH", ""),
+		JWTIssuer:              getEnv("JWT_ISSUER", "learning-go"),
+		JWTAudience:            getEnv("JWT_AUDIENCE", "learning-go-api"),
+
+		AuthCookieEnabled: getEnvAsBool("AUTH_COOKIE_ENABLED", false),
+		AuthCookieSecure:  getEnvAsBool("AUTH_COOKIE_SECURE", true),
+
+		RefreshTokenExpirationInSeconds:       getEnvAsInt("REFRESH_TOKEN_EXPIRY", 3600*24*30),
+		VerificationTokenExpirationInSeconds:  getEnvAsInt("VERIFICATION_TOKEN_EXPIRY", 3600*24),
+		PasswordResetTokenExpirationInSeconds: getEnvAsInt("PASSWORD_RESET_TOKEN_EXPIRY", 3600),
+		RequireEmailVerification:              getEnvAsBool("REQUIRE_EMAIL_VERIFICATION", false),
+
+		PasswordResetURL: getEnv("PASSWORD_RESET_URL", "http://localhost:3000/reset-password"),
+
+		DBMaxOpenConns:             getEnvAsInt("DB_MAX_OPEN_CONNS", 25),
+		DBMaxIdleConns:             getEnvAsInt("DB_MAX_IDLE_CONNS", 25),
+		DBConnMaxLifetimeInSeconds: getEnvAsInt("DB_CONN_MAX_LIFETIME", 300),
+		DBConnMaxIdleTimeInSeconds: getEnvAsInt("DB_CONN_MAX_IDLE_TIME", 60),
+		DBSlowQueryThresholdInMs:   getEnvAsInt("DB_SLOW_QUERY_THRESHOLD_MS", 0),
+
+		AuthRateLimit:                getEnvAsInt("AUTH_RATE_LIMIT", 5),
+		AuthRateLimitWindowInSeconds: getEnvAsInt("AUTH_RATE_LIMIT_WINDOW", 60),
+
+		PasswordMinLength:       getEnvAsInt("PASSWORD_MIN_LENGTH", 8),
+		PasswordRequiredClasses: getEnvAsInt("PASSWORD_REQUIRED_CLASSES", 3),
+		BcryptCost:              getEnvAsInt("BCRYPT_COST", 10),
+
+		TLSCertFile:      getEnv("TLS_CERT_FILE", ""),
+		TLSKeyFile:       getEnv("TLS_KEY_FILE", ""),
+		HTTPRedirectAddr: getEnv("HTTP_REDIRECT_ADDR", ""),
+
+		MaxRequestBodyBytes:       getEnvAsInt("MAX_REQUEST_BODY_BYTES", 1<<20),
+		DisallowUnknownJSONFields: getEnvAsBool("DISALLOW_UNKNOWN_JSON_FIELDS", false),
+
+		IdempotencyKeyTTLInSeconds: getEnvAsInt("IDEMPOTENCY_KEY_TTL", 3600),
+
+		GzipMinSizeBytes: getEnvAsInt("GZIP_MIN_SIZE", 1024),
+
+		// A TTL of 0 leaves the product cache off
+		ProductCacheTTLInSeconds: getEnvAsInt("PRODUCT_CACHE_TTL", 0),
+		ProductCacheSize:         getEnvAsInt("PRODUCT_CACHE_SIZE", 1000),
+
+		LogLevel: getEnv("LOG_LEVEL", "info"),
+
+		SMTPHost:     getEnv("SMTP_HOST", ""),
+		SMTPPort:     getEnv("SMTP_PORT", "587"),
+		SMTPUsername: getEnv("SMTP_USERNAME", ""),
+		SMTPPassword: getEnv("SMTP_PASSWORD", ""),
+		SMTPFrom:     getEnv("SMTP_FROM", ""),
+
+		DebugInfoEnabled: getEnvAsBool("DEBUG_INFO_ENABLED", false),
+
+		RunMigrations: getEnvAsBool("RUN_MIGRATIONS", false),
+		SeedData:      getEnvAsBool("SEED_DATA", false),
+
+		CORSAllowedOrigins: getEnvAsSlice("CORS_ALLOWED_ORIGINS", []string{"*"}),
+		CORSAllowedMethods: getEnvAsSlice("CORS_ALLOWED_METHODS", []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}),
+		CORSAllowedHeaders: getEnvAsSlice("CORS_ALLOWED_HEADERS", []string{"Content-Type", "Authorization"}),
+
+		CORSCredentialsOrigins: getEnvAsSlice("CORS_CREDENTIALS_ORIGINS", nil),
+		CORSCredentialsPaths:   getEnvAsSlice("CORS_CREDENTIALS_PATHS", []string{"/login", "/refresh", "/me", "/change-password", "/products", "/users"}),
 	}
 }
 
@@ -55,3 +193,41 @@ func getEnvAsInt(key string, fallback int64) int64 {
 
 	return fallback
 }
+
+func getEnvAsBool(key string, fallback bool) bool {
+	if value, ok := os.LookupEnv(key); ok {
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fallback
+		}
+
+		return b
+	}
+
+	return fallback
+}
+
+func getEnvAsSlice(key string, fallback []string) []string {
+	if value, ok := os.LookupEnv(key); ok {
+		var values []string
+		for _, v := range strings.Split(value, ",") {
+			if v = strings.TrimSpace(v); v != "" {
+				values = append(values, v)
+			}
+		}
+
+		return values
+	}
+
+	return fallback
+}
+
+// normalizePath gives a path a leading slash and no trailing one, so "api",
+// "/api/" and "/api" mount the same way; an empty path is the root
+func normalizePath(path string) string {
+	if path = strings.Trim(path, "/"); path == "" {
+		return ""
+	}
+
+	return "/" + path
+}
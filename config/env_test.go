@@ -0,0 +1,21 @@
+package config
+
+import "testing"
+
+func TestNormalizePath(t *testing.T) {
+	for _, tt := range []struct {
+		path     string
+		expected string
+	}{
+		{"/api", "/api"},
+		{"api", "/api"},
+		{"/api/", "/api"},
+		{"store/api/", "/store/api"},
+		{"/", ""},
+		{"", ""},
+	} {
+		if normalized := normalizePath(tt.path); normalized != tt.expected {
+			t.Errorf("%q: expected %q, got %q", tt.path, tt.expected, normalized)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// Supported aggregate functions
+const (
+	AggregateSum = "SUM"
+	AggregateAvg = "AVG"
+	AggregateMin = "MIN"
+	AggregateMax = "MAX"
+)
+
+// Aggregate runs fn(column) over the matching records. An aggregate over no
+// rows is NULL in SQL and is reported as 0.
+func Aggregate(db Querier, tableName, fn, column string, options *QueryOptions) (float64, error) {
+	fn = strings.ToUpper(fn)
+	switch fn {
+	case AggregateSum, AggregateAvg, AggregateMin, AggregateMax:
+	default:
+		return 0, fmt.Errorf("unsupported aggregate function: %s", fn)
+	}
+
+	table, err := validateIdent(tableName)
+	if err != nil {
+		return 0, err
+	}
+
+	column, err = validateIdent(column)
+	if err != nil {
+		return 0, err
+	}
+
+	whereClause, args, err := buildWhereClause(options)
+	if err != nil {
+		return 0, err
+	}
+
+	query := fmt.Sprintf("SELECT %s(%s) FROM %s%s", fn, column, table, whereClause)
+
+	var result sql.NullFloat64
+	if err := runQueryRow(db, query, args...).Scan(&result); err != nil {
+		return 0, fmt.Errorf("failed to aggregate records: %w", err)
+	}
+
+	if !result.Valid {
+		return 0, nil
+	}
+
+	return result.Float64, nil
+}
+
+// Sum returns SUM(column) over the matching records
+func Sum(db Querier, tableName, column string, options *QueryOptions) (float64, error) {
+	return Aggregate(db, tableName, AggregateSum, column, options)
+}
+
+// Avg returns AVG(column) over the matching records
+func Avg(db Querier, tableName, column string, options *QueryOptions) (float64, error) {
+	return Aggregate(db, tableName, AggregateAvg, column, options)
+}
+
+// Min returns MIN(column) over the matching records
+func Min(db Querier, tableName, column string, options *QueryOptions) (float64, error) {
+	return Aggregate(db, tableName, AggregateMin, column, options)
+}
+
+// Max returns MAX(column) over the matching records
+func Max(db Querier, tableName, column string, options *QueryOptions) (float64, error) {
+	return Aggregate(db, tableName, AggregateMax, column, options)
+}
@@ -0,0 +1,191 @@
+package db
+
+import (
+	"container/list"
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Cache holds the records of the read-through finders. Keys start with the
+// table name and a colon, so DeletePrefix evicts a whole table. The default
+// is LRUCache; a shared cache is needed once the API runs on several
+// instances.
+type Cache interface {
+	Get(key string) (any, bool)
+	Set(key string, value any, ttl time.Duration)
+	DeletePrefix(prefix string)
+}
+
+// ReadCache puts a Cache in front of CachedFindByPK and CachedFindOne. A nil
+// *ReadCache is valid and reads straight from the database, so a store can
+// leave it unset for reads that must always be fresh.
+//
+// Every cache is flushed for a table when the write helpers of this package
+// (InsertOne, UpdateData, DeleteData, ...) run against it. Writes sent as raw
+// SQL have to call InvalidateTable themselves. Eviction happens when the
+// statement runs, not when its transaction commits, so a read in between can
+// cache the old row until the TTL expires.
+type ReadCache struct {
+	cache Cache
+	ttl   time.Duration
+}
+
+var (
+	readCachesMu sync.RWMutex
+	readCaches   []*ReadCache
+)
+
+// NewReadCache caches records for ttl and registers the cache for the
+// invalidation of the write helpers
+func NewReadCache(cache Cache, ttl time.Duration) *ReadCache {
+	c := &ReadCache{cache: cache, ttl: ttl}
+
+	readCachesMu.Lock()
+	readCaches = append(readCaches, c)
+	readCachesMu.Unlock()
+
+	return c
+}
+
+// Invalidate evicts every cached record of a table
+func (c *ReadCache) Invalidate(tableName string) {
+	if c != nil {
+		c.cache.DeletePrefix(tableName + ":")
+	}
+}
+
+// InvalidateTable evicts a table from every ReadCache. The write helpers call
+// it; code changing a table with raw SQL has to call it too.
+func InvalidateTable(tableName string) {
+	readCachesMu.RLock()
+	defer readCachesMu.RUnlock()
+
+	for _, c := range readCaches {
+		c.Invalidate(tableName)
+	}
+}
+
+// CachedFindByPK is FindByPK through a ReadCache
+func CachedFindByPK[T any](c *ReadCache, db Querier, tableName string, pk interface{}) (*T, error) {
+	return CachedFindOne[T](c, db, tableName, &QueryOptions{
+		Where:     "id = ?",
+		WhereArgs: []interface{}{pk},
+	})
+}
+
+// CachedFindOne is FindOne through a ReadCache, keyed by the table, T and the
+// options. Misses (sql.ErrNoRows) and locking reads are never cached.
+func CachedFindOne[T any](c *ReadCache, db Querier, tableName string, options *QueryOptions) (*T, error) {
+	if c == nil || (options != nil && options.LockMode != NoLock) {
+		return FindOneContext[T](context.Background(), db, tableName, options)
+	}
+
+	key, err := cacheKey[T](tableName, options)
+	if err != nil {
+		return nil, err
+	}
+
+	if value, ok := c.cache.Get(key); ok {
+		// Stored by value, every caller gets its own copy
+		record := value.(T)
+		return &record, nil
+	}
+
+	record, err := FindOneContext[T](context.Background(), db, tableName, options)
+	if err != nil {
+		return nil, err
+	}
+
+	c.cache.Set(key, *record, c.ttl)
+
+	return record, nil
+}
+
+// cacheKey identifies a read by table, record type and options
+func cacheKey[T any](tableName string, options *QueryOptions) (string, error) {
+	encoded, err := json.Marshal(options)
+	if err != nil {
+		return "", err
+	}
+
+	return tableName + ":" + reflect.TypeFor[T]().String() + ":" + string(encoded), nil
+}
+
+type lruEntry struct {
+	key       string
+	value     any
+	expiresAt time.Time
+}
+
+// LRUCache is an in-process Cache holding at most capacity entries, dropping
+// the least recently used one when full
+type LRUCache struct {
+	mu       sync.Mutex
+	capacity int
+	order    *list.List // front is the most recently used
+	entries  map[string]*list.Element
+}
+
+func NewLRUCache(capacity int) *LRUCache {
+	return &LRUCache{
+		capacity: capacity,
+		order:    list.New(),
+		entries:  make(map[string]*list.Element),
+	}
+}
+
+func (c *LRUCache) Get(key string) (any, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+
+	entry := element.Value.(*lruEntry)
+	if time.Now().After(entry.expiresAt) {
+		c.remove(element)
+		return nil, false
+	}
+
+	c.order.MoveToFront(element)
+	return entry.value, true
+}
+
+func (c *LRUCache) Set(key string, value any, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if element, ok := c.entries[key]; ok {
+		element.Value = &lruEntry{key: key, value: value, expiresAt: time.Now().Add(ttl)}
+		c.order.MoveToFront(element)
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(&lruEntry{key: key, value: value, expiresAt: time.Now().Add(ttl)})
+
+	if c.order.Len() > c.capacity {
+		c.remove(c.order.Back())
+	}
+}
+
+func (c *LRUCache) DeletePrefix(prefix string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for key, element := range c.entries {
+		if strings.HasPrefix(key, prefix) {
+			c.remove(element)
+		}
+	}
+}
+
+func (c *LRUCache) remove(element *list.Element) {
+	c.order.Remove(element)
+	delete(c.entries, element.Value.(*lruEntry).key)
+}
@@ -0,0 +1,87 @@
+package db
+
+import (
+	"database/sql/driver"
+	"testing"
+	"time"
+)
+
+func TestReadCache(t *testing.T) {
+	columns := []string{"id", "name"}
+
+	t.Run("should serve a repeated read from the cache", func(t *testing.T) {
+		conn, fake := newFakeDB(t, columns, []driver.Value{int64(1), "chair"})
+		cache := NewReadCache(NewLRUCache(10), time.Minute)
+
+		for range 2 {
+			record, err := CachedFindByPK[nullableRecord](cache, conn, "products", 1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if record.Name != "chair" {
+				t.Errorf("unexpected record %+v", record)
+			}
+		}
+
+		if len(fake.Queries) != 1 {
+			t.Errorf("expected a single query, got %v", fake.Queries)
+		}
+	})
+
+	t.Run("should evict a table when it is written", func(t *testing.T) {
+		conn, fake := newFakeDB(t, columns, []driver.Value{int64(1), "chair"})
+		cache := NewReadCache(NewLRUCache(10), time.Minute)
+
+		if _, err := CachedFindByPK[nullableRecord](cache, conn, "products", 1); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := DeleteCount(conn, "products", &QueryOptions{Where: "id = ?", WhereArgs: []interface{}{2}}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := CachedFindByPK[nullableRecord](cache, conn, "products", 1); err != nil {
+			t.Fatal(err)
+		}
+
+		if len(fake.Queries) != 3 {
+			t.Errorf("expected the read after the write to hit the database, got %v", fake.Queries)
+		}
+	})
+
+	t.Run("should bypass a nil cache", func(t *testing.T) {
+		conn, fake := newFakeDB(t, columns, []driver.Value{int64(1), "chair"})
+
+		for range 2 {
+			if _, err := CachedFindByPK[nullableRecord](nil, conn, "products", 1); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		if len(fake.Queries) != 2 {
+			t.Errorf("expected two queries, got %v", fake.Queries)
+		}
+	})
+
+	t.Run("should drop the least recently used entry", func(t *testing.T) {
+		cache := NewLRUCache(2)
+		cache.Set("a", 1, time.Minute)
+		cache.Set("b", 2, time.Minute)
+		cache.Get("a")
+		cache.Set("c", 3, time.Minute)
+
+		if _, ok := cache.Get("b"); ok {
+			t.Error("expected b to be evicted")
+		}
+		if _, ok := cache.Get("a"); !ok {
+			t.Error("expected a to be kept")
+		}
+	})
+
+	t.Run("should expire an entry after its ttl", func(t *testing.T) {
+		cache := NewLRUCache(2)
+		cache.Set("a", 1, -time.Second)
+
+		if _, ok := cache.Get("a"); ok {
+			t.Error("expected a to have expired")
+		}
+	})
+}
@@ -0,0 +1,60 @@
+package db
+
+import (
+	"fmt"
+	"strings"
+)
+
+// WhereClause is one condition of QueryOptions.Conditions with its own args
+type WhereClause struct {
+	Expr string        `json:"expr"`
+	Args []interface{} `json:"args,omitempty"`
+}
+
+// In builds "column IN (?, ?, ...)" with one placeholder per value, for use
+// as a Where condition. An empty list renders a condition that matches nothing.
+func In(column string, values []interface{}) (string, []interface{}, error) {
+	col, err := validateIdent(column)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if len(values) == 0 {
+		return "1 = 0", nil, nil
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(values)), ", ")
+
+	return fmt.Sprintf("%s IN (%s)", col, placeholders), values, nil
+}
+
+// Between builds "column BETWEEN ? AND ?", both bounds included
+func Between(column string, lo, hi interface{}) (string, []interface{}, error) {
+	col, err := validateIdent(column)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return col + " BETWEEN ? AND ?", []interface{}{lo, hi}, nil
+}
+
+// IsNull builds "column IS NULL", which "column = ?" with a nil arg can't
+// express
+func IsNull(column string) (string, []interface{}, error) {
+	col, err := validateIdent(column)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return col + " IS NULL", nil, nil
+}
+
+// IsNotNull builds "column IS NOT NULL"
+func IsNotNull(column string) (string, []interface{}, error) {
+	col, err := validateIdent(column)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return col + " IS NOT NULL", nil, nil
+}
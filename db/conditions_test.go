@@ -0,0 +1,127 @@
+package db
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBuildWhereClause(t *testing.T) {
+	t.Run("should AND the conditions and flatten their args in order", func(t *testing.T) {
+		where, args, err := buildWhereClause(&QueryOptions{
+			Where:     "quantity > ?",
+			WhereArgs: []interface{}{0},
+			Conditions: []WhereClause{
+				{Expr: "price BETWEEN ? AND ?", Args: []interface{}{10, 20}},
+				{Expr: "name LIKE ?", Args: []interface{}{"%chair%"}},
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected := " WHERE (quantity > ?) AND (price BETWEEN ? AND ?) AND (name LIKE ?)"
+		if where != expected {
+			t.Errorf("expected %q, got %q", expected, where)
+		}
+
+		if !reflect.DeepEqual(args, []interface{}{0, 10, 20, "%chair%"}) {
+			t.Errorf("unexpected args %v", args)
+		}
+	})
+
+	t.Run("should use a single condition as is", func(t *testing.T) {
+		where, args, err := buildWhereClause(&QueryOptions{
+			Conditions: []WhereClause{{Expr: "id = ?", Args: []interface{}{1}}},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if where != " WHERE id = ?" {
+			t.Errorf("expected %q, got %q", " WHERE id = ?", where)
+		}
+		if len(args) != 1 {
+			t.Errorf("expected 1 arg, got %v", args)
+		}
+	})
+
+	t.Run("should keep the soft delete filter", func(t *testing.T) {
+		where, _, err := buildWhereClause(&QueryOptions{
+			Conditions:       []WhereClause{{Expr: "id = ?", Args: []interface{}{1}}},
+			SoftDeleteColumn: "deleted_at",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected := " WHERE (id = ?) AND deleted_at IS NULL"
+		if where != expected {
+			t.Errorf("expected %q, got %q", expected, where)
+		}
+	})
+}
+
+func TestConditionHelpers(t *testing.T) {
+	t.Run("should keep the bounds of Between in order", func(t *testing.T) {
+		expr, args, err := Between("createdAt", "2026-01-01", "2026-12-31")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if expr != "createdAt BETWEEN ? AND ?" {
+			t.Errorf("expected %q, got %q", "createdAt BETWEEN ? AND ?", expr)
+		}
+		if !reflect.DeepEqual(args, []interface{}{"2026-01-01", "2026-12-31"}) {
+			t.Errorf("unexpected args %v", args)
+		}
+	})
+
+	t.Run("should build NULL checks without args", func(t *testing.T) {
+		for _, tt := range []struct {
+			build    func(string) (string, []interface{}, error)
+			expected string
+		}{
+			{IsNull, "deleted_at IS NULL"},
+			{IsNotNull, "deleted_at IS NOT NULL"},
+		} {
+			expr, args, err := tt.build("deleted_at")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if expr != tt.expected || len(args) != 0 {
+				t.Errorf("expected %q without args, got %q %v", tt.expected, expr, args)
+			}
+		}
+	})
+
+	t.Run("should compose with the other conditions", func(t *testing.T) {
+		between, betweenArgs, _ := Between("price", 10, 20)
+		isNull, _, _ := IsNull("deleted_at")
+
+		where, args, err := buildWhereClause(&QueryOptions{
+			Conditions: []WhereClause{
+				{Expr: between, Args: betweenArgs},
+				{Expr: isNull},
+				{Expr: "quantity > ?", Args: []interface{}{0}},
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected := " WHERE (price BETWEEN ? AND ?) AND (deleted_at IS NULL) AND (quantity > ?)"
+		if where != expected {
+			t.Errorf("expected %q, got %q", expected, where)
+		}
+		if !reflect.DeepEqual(args, []interface{}{10, 20, 0}) {
+			t.Errorf("unexpected args %v", args)
+		}
+	})
+
+	t.Run("should reject an invalid column", func(t *testing.T) {
+		if _, _, err := Between("price; DROP TABLE products", 1, 2); err == nil {
+			t.Error("expected an error for an invalid column")
+		}
+	})
+}
@@ -0,0 +1,25 @@
+package db
+
+import (
+	"context"
+	"time"
+)
+
+// DefaultTimeout, when non-zero, is the deadline the context variants give a
+// query whose context has none, so a hung query can't hold a connection
+// forever. The variants without a context use it too.
+var DefaultTimeout time.Duration
+
+// withDefaultTimeout derives a context with DefaultTimeout unless ctx already
+// has a deadline or the default is disabled
+func withDefaultTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if DefaultTimeout <= 0 {
+		return ctx, func() {}
+	}
+
+	if _, ok := ctx.Deadline(); ok {
+		return ctx, func() {}
+	}
+
+	return context.WithTimeout(ctx, DefaultTimeout)
+}
@@ -0,0 +1,50 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestWithDefaultTimeout(t *testing.T) {
+	defer func(timeout time.Duration) { DefaultTimeout = timeout }(DefaultTimeout)
+
+	t.Run("should leave the context alone when disabled", func(t *testing.T) {
+		DefaultTimeout = 0
+
+		ctx, cancel := withDefaultTimeout(context.Background())
+		defer cancel()
+
+		if _, ok := ctx.Deadline(); ok {
+			t.Error("expected no deadline")
+		}
+	})
+
+	t.Run("should add a deadline to a context without one", func(t *testing.T) {
+		DefaultTimeout = time.Second
+
+		ctx, cancel := withDefaultTimeout(context.Background())
+		defer cancel()
+
+		deadline, ok := ctx.Deadline()
+		if !ok || time.Until(deadline) > time.Second {
+			t.Errorf("expected a deadline within %v, got %v", DefaultTimeout, deadline)
+		}
+	})
+
+	t.Run("should keep an existing deadline", func(t *testing.T) {
+		DefaultTimeout = time.Second
+
+		parent, cancelParent := context.WithTimeout(context.Background(), time.Hour)
+		defer cancelParent()
+
+		ctx, cancel := withDefaultTimeout(parent)
+		defer cancel()
+
+		deadline, _ := ctx.Deadline()
+		expected, _ := parent.Deadline()
+		if !deadline.Equal(expected) {
+			t.Errorf("expected deadline %v, got %v", expected, deadline)
+		}
+	})
+}
@@ -0,0 +1,56 @@
+package db
+
+import "fmt"
+
+// Dialect identifies the SQL flavour of the database, for the few clauses
+// that differ between them
+type Dialect string
+
+const (
+	MySQL      Dialect = "mysql"
+	PostgreSQL Dialect = "postgres"
+	SQLite     Dialect = "sqlite"
+)
+
+// CurrentDialect is the dialect queries are rendered for
+var CurrentDialect = MySQL
+
+// SupportsReturning reports whether UPDATE and DELETE take a RETURNING
+// clause. Without it UpdateData, DeleteData and SoftDelete select the affected
+// rows in a separate query of the same transaction instead.
+func (d Dialect) SupportsReturning() bool {
+	return d != MySQL
+}
+
+// LockMode selects the row locking clause appended to a SELECT. Locks are
+// held until the surrounding transaction ends, so they only make sense
+// inside one.
+type LockMode int
+
+const (
+	NoLock LockMode = iota
+	ForUpdate
+	ForShare
+)
+
+// lockClause renders the locking clause (with its leading space) for the
+// current dialect. SQLite locks the whole database per transaction and has no
+// row locks, so the clause is omitted there.
+func lockClause(mode LockMode) (string, error) {
+	if mode == NoLock || CurrentDialect == SQLite {
+		return "", nil
+	}
+
+	switch mode {
+	case ForUpdate:
+		return " FOR UPDATE", nil
+	case ForShare:
+		if CurrentDialect == MySQL {
+			// Understood by MySQL 5.7 as well as 8, unlike FOR SHARE
+			return " LOCK IN SHARE MODE", nil
+		}
+		return " FOR SHARE", nil
+	default:
+		return "", fmt.Errorf("unknown lock mode %d", mode)
+	}
+}
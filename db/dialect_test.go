@@ -0,0 +1,34 @@
+package db
+
+import "testing"
+
+func TestLockMode(t *testing.T) {
+	defer func(dialect Dialect) { CurrentDialect = dialect }(CurrentDialect)
+
+	tests := []struct {
+		dialect  Dialect
+		mode     LockMode
+		expected string
+	}{
+		{MySQL, ForUpdate, "SELECT * FROM products WHERE id = ? LIMIT 1 FOR UPDATE"},
+		{MySQL, ForShare, "SELECT * FROM products WHERE id = ? LIMIT 1 LOCK IN SHARE MODE"},
+		{PostgreSQL, ForShare, "SELECT * FROM products WHERE id = ? LIMIT 1 FOR SHARE"},
+		{SQLite, ForUpdate, "SELECT * FROM products WHERE id = ? LIMIT 1"},
+		{MySQL, NoLock, "SELECT * FROM products WHERE id = ? LIMIT 1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.dialect), func(t *testing.T) {
+			CurrentDialect = tt.dialect
+
+			query, err := buildSelectQuery("products", &QueryOptions{Limit: 1, LockMode: tt.mode}, " WHERE id = ?")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if query != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, query)
+			}
+		})
+	}
+}
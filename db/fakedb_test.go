@@ -0,0 +1,15 @@
+package db
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"testing"
+
+	"github.com/Jay1570/learning-go/testutil/fakedb"
+)
+
+// newFakeDB opens a fakedb database whose queries all return the given rows
+func newFakeDB(t *testing.T, columns []string, rows ...[]driver.Value) (*sql.DB, *fakedb.DB) {
+	t.Helper()
+	return fakedb.New(t, columns, rows...)
+}
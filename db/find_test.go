@@ -0,0 +1,85 @@
+package db
+
+import (
+	"database/sql/driver"
+	"testing"
+)
+
+func TestFindByIDs(t *testing.T) {
+	t.Run("should return the records in the requested order", func(t *testing.T) {
+		conn, fake := newFakeDB(t, []string{"id", "name"},
+			[]driver.Value{int64(1), "chair"},
+			[]driver.Value{int64(2), "table"},
+			[]driver.Value{int64(3), "lamp"},
+		)
+
+		records, err := FindByIDs[nullableRecord](conn, "products", []interface{}{3, 1, 4, 3})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(records) != 2 || records[0].Name != "lamp" || records[1].Name != "chair" {
+			t.Errorf("unexpected records %+v", records)
+		}
+
+		expected := "SELECT * FROM products WHERE id IN (?, ?, ?, ?)"
+		if fake.Queries[0] != expected {
+			t.Errorf("expected %q, got %q", expected, fake.Queries[0])
+		}
+	})
+
+	t.Run("should not query without ids", func(t *testing.T) {
+		conn, fake := newFakeDB(t, nil)
+
+		records, err := FindByIDs[nullableRecord](conn, "products", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if records == nil || len(records) != 0 {
+			t.Errorf("expected an empty slice, got %v", records)
+		}
+		if len(fake.Queries) != 0 {
+			t.Errorf("expected no queries, got %v", fake.Queries)
+		}
+	})
+}
+
+func TestFindByPKs(t *testing.T) {
+	t.Run("should match every key column", func(t *testing.T) {
+		conn, fake := newFakeDB(t, []string{"id", "name"}, []driver.Value{int64(1), "chair"})
+
+		record, err := FindByPKs[nullableRecord](conn, "product_tags", map[string]interface{}{"tagId": 2, "productId": 1})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if record.Name != "chair" {
+			t.Errorf("unexpected record %+v", record)
+		}
+
+		expected := "SELECT * FROM product_tags WHERE productId = ? AND tagId = ? LIMIT 1"
+		if fake.Queries[0] != expected {
+			t.Errorf("expected %q, got %q", expected, fake.Queries[0])
+		}
+		if fake.Args[0][0] != int64(1) || fake.Args[0][1] != int64(2) {
+			t.Errorf("expected the args in column order, got %v", fake.Args[0])
+		}
+	})
+
+	t.Run("should reject an invalid column", func(t *testing.T) {
+		conn, _ := newFakeDB(t, []string{"id"})
+
+		_, err := FindByPKs[nullableRecord](conn, "product_tags", map[string]interface{}{"id = 1 OR 1": 1})
+		if err == nil {
+			t.Error("expected an error for an invalid column")
+		}
+	})
+
+	t.Run("should require a key", func(t *testing.T) {
+		conn, _ := newFakeDB(t, []string{"id"})
+
+		if _, err := FindByPKs[nullableRecord](conn, "product_tags", nil); err == nil {
+			t.Error("expected an error without keys")
+		}
+	})
+}
@@ -0,0 +1,22 @@
+package db
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var identPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// validateIdent checks a table or column name, optionally qualified with a
+// table or alias ("u.id"), and returns it unchanged. The pattern only lets
+// through names that are safe to interpolate into SQL without quoting.
+func validateIdent(name string) (string, error) {
+	for _, part := range strings.Split(name, ".") {
+		if !identPattern.MatchString(part) {
+			return "", fmt.Errorf("invalid identifier: %q", name)
+		}
+	}
+
+	return name, nil
+}
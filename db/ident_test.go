@@ -0,0 +1,34 @@
+package db
+
+import "testing"
+
+func TestValidateIdent(t *testing.T) {
+	for _, tt := range []struct {
+		name  string
+		valid bool
+	}{
+		{"products", true},
+		{"createdAt", true},
+		{"_private", true},
+		{"product_tags", true},
+		{"u.id", true},
+		{"", false},
+		{"1column", false},
+		{"u.", false},
+		{".id", false},
+		{"a.b.c", true},
+		{"name desc", false},
+		{"`name`", false},
+		{"id; DROP TABLE users", false},
+		{"id = 1 OR 1", false},
+		{"naïve", false},
+	} {
+		ident, err := validateIdent(tt.name)
+		if tt.valid && (err != nil || ident != tt.name) {
+			t.Errorf("%q: expected it to be accepted, got %q, %v", tt.name, ident, err)
+		}
+		if !tt.valid && err == nil {
+			t.Errorf("%q: expected it to be rejected", tt.name)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"reflect"
+)
+
+// FindAllIter streams the matching records one row at a time instead of
+// loading them all into a slice, for exports of large tables:
+//
+//	for product, err := range db.FindAllIter[types.Product](conn, "products", nil) {
+//		if err != nil {
+//			return err
+//		}
+//		...
+//	}
+//
+// The rows are closed when the loop ends, including on break. An error is
+// yielded once with the zero value and ends the iteration.
+func FindAllIter[T any](db Querier, tableName string, options *QueryOptions) iter.Seq2[T, error] {
+	return FindAllIterContext[T](context.Background(), db, tableName, options)
+}
+
+// FindAllIterContext is like FindAllIter but runs under ctx. DefaultTimeout
+// does not apply since a stream is expected to outlive it; give ctx a
+// deadline to bound it.
+func FindAllIterContext[T any](ctx context.Context, db Querier, tableName string, options *QueryOptions) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		var zero T
+
+		whereClause, args, err := buildWhereClause(options)
+		if err != nil {
+			yield(zero, err)
+			return
+		}
+
+		query, err := buildSelectQuery(tableName, options, whereClause)
+		if err != nil {
+			yield(zero, err)
+			return
+		}
+
+		rows, err := runQueryContext(ctx, db, query, args...)
+		if err != nil {
+			yield(zero, fmt.Errorf("failed to query records: %w", err))
+			return
+		}
+		defer rows.Close()
+
+		columns, err := rows.Columns()
+		if err != nil {
+			yield(zero, fmt.Errorf("failed to read columns: %w", err))
+			return
+		}
+		fieldIndexes := columnFields(columns, structFields(reflect.ValueOf(&zero).Elem()))
+
+		for rows.Next() {
+			var item T
+			if err := scanRow(rows, fieldIndexes, &item); err != nil {
+				yield(zero, err)
+				return
+			}
+
+			if !yield(item, nil) {
+				return
+			}
+		}
+
+		if err := rows.Err(); err != nil {
+			yield(zero, err)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package db
+
+import (
+	"database/sql/driver"
+	"testing"
+)
+
+func TestFindAllIter(t *testing.T) {
+	conn, _ := newFakeDB(t, []string{"id", "name"},
+		[]driver.Value{int64(1), "chair"},
+		[]driver.Value{int64(2), "table"},
+		[]driver.Value{int64(3), "lamp"},
+	)
+
+	t.Run("should yield every row", func(t *testing.T) {
+		var names []string
+		for record, err := range FindAllIter[nullableRecord](conn, "products", nil) {
+			if err != nil {
+				t.Fatal(err)
+			}
+			names = append(names, record.Name)
+		}
+
+		if len(names) != 3 || names[2] != "lamp" {
+			t.Errorf("unexpected records %v", names)
+		}
+	})
+
+	t.Run("should stop and release the connection on break", func(t *testing.T) {
+		for range 3 {
+			for _, err := range FindAllIter[nullableRecord](conn, "products", nil) {
+				if err != nil {
+					t.Fatal(err)
+				}
+				break
+			}
+		}
+
+		if inUse := conn.Stats().InUse; inUse != 0 {
+			t.Errorf("expected no connections in use, got %d", inUse)
+		}
+	})
+
+	t.Run("should yield an invalid table name as an error", func(t *testing.T) {
+		for _, err := range FindAllIter[nullableRecord](conn, "products; DROP TABLE users", nil) {
+			if err == nil {
+				t.Error("expected an error")
+			}
+		}
+	})
+}
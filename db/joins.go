@@ -1,8 +1,10 @@
 package db
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
+	"strings"
 )
 
 // JoinType represents the type of SQL join
@@ -13,36 +15,67 @@ const (
 	LeftJoin  JoinType = "LEFT JOIN"
 	RightJoin JoinType = "RIGHT JOIN"
 	FullJoin  JoinType = "FULL OUTER JOIN"
+	CrossJoin JoinType = "CROSS JOIN"
 )
 
 // JoinClause represents a single join operation
 type JoinClause struct {
-	Type      JoinType // Type of join (INNER, LEFT, RIGHT, FULL)
+	Type      JoinType // Type of join (INNER, LEFT, RIGHT, FULL, CROSS)
 	Table     string   // Table to join
+	Alias     string   // Optional alias for the joined table (e.g., "o")
 	Condition string   // Join condition (e.g., "users.id = orders.user_id")
 }
 
+// As returns a copy of the join clause with the given table alias
+func (jc JoinClause) As(alias string) JoinClause {
+	jc.Alias = alias
+	return jc
+}
+
+// CTE is a named common table expression, given either as a builder or as
+// raw SQL (e.g. the UNION ALL body of a recursive CTE)
+type CTE struct {
+	Name     string
+	Builder  *JoinBuilder
+	Subquery *Subquery
+}
+
 // QueryOptionsWithJoins extends QueryOptions to support joins
 type QueryOptionsWithJoins struct {
-	Joins     []JoinClause  `json:"joins,omitempty"`
-	Where     string        `json:"where,omitempty"`
-	WhereArgs []interface{} `json:"whereArgs,omitempty"`
-	OrderBy   string        `json:"orderBy,omitempty"`
-	Limit     int           `json:"limit,omitempty"`
-	Offset    int           `json:"offset,omitempty"`
-	Select    string        `json:"select,omitempty"` // Custom SELECT clause
+	With      []CTE           `json:"-"`               // Common table expressions emitted before the query
+	Recursive bool            `json:"-"`               // Emit WITH RECURSIVE
+	From      *Subquery       `json:"-"`               // Optional derived table used instead of the base table
+	Alias     string          `json:"alias,omitempty"` // Optional alias for the base table
+	Joins     []JoinClause    `json:"joins,omitempty"`
+	Where     string          `json:"where,omitempty"`
+	WhereArgs []interface{}   `json:"whereArgs,omitempty"`
+	OrderBy   string          `json:"orderBy,omitempty"`
+	Order     []OrderByClause `json:"order,omitempty"` // Validated ORDER BY, takes precedence over OrderBy
+	Limit     int             `json:"limit,omitempty"`
+	Offset    int             `json:"offset,omitempty"`
+	Select    string          `json:"select,omitempty"`   // Custom SELECT clause
+	Distinct  bool            `json:"distinct,omitempty"` // Emit SELECT DISTINCT
+	// CountDistinct counts distinct values of a column (e.g. "u.id") instead of
+	// joined rows, so one-to-many joins don't inflate the total
+	CountDistinct string `json:"countDistinct,omitempty"`
 }
 
 // FindAllWithJoins performs a query with joins
-func FindAllWithJoins[T any](db *sql.DB, tableName string, options *QueryOptionsWithJoins) ([]T, error) {
-	query := buildJoinQuery(tableName, options)
+func FindAllWithJoins[T any](db Querier, tableName string, options *QueryOptionsWithJoins) ([]T, error) {
+	return FindAllWithJoinsContext[T](context.Background(), db, tableName, options)
+}
 
-	args := []interface{}{}
-	if options != nil && options.WhereArgs != nil {
-		args = options.WhereArgs
+// FindAllWithJoinsContext is like FindAllWithJoins but runs under ctx
+func FindAllWithJoinsContext[T any](ctx context.Context, db Querier, tableName string, options *QueryOptionsWithJoins) ([]T, error) {
+	ctx, cancel := withDefaultTimeout(ctx)
+	defer cancel()
+
+	query, args, err := buildJoinQuery(tableName, options)
+	if err != nil {
+		return nil, err
 	}
 
-	rows, err := db.Query(query, args...)
+	rows, err := runQueryContext(ctx, db, query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query records with joins: %w", err)
 	}
@@ -52,25 +85,35 @@ func FindAllWithJoins[T any](db *sql.DB, tableName string, options *QueryOptions
 }
 
 // FindAllAndCountWithJoins performs a count and query with joins
-func FindAllAndCountWithJoins[T any](db *sql.DB, tableName string, options *QueryOptionsWithJoins) (*CountResult[T], error) {
+func FindAllAndCountWithJoins[T any](db Querier, tableName string, options *QueryOptionsWithJoins) (*CountResult[T], error) {
+	return FindAllAndCountWithJoinsContext[T](context.Background(), db, tableName, options)
+}
+
+// FindAllAndCountWithJoinsContext is like FindAllAndCountWithJoins but runs under ctx
+func FindAllAndCountWithJoinsContext[T any](ctx context.Context, db Querier, tableName string, options *QueryOptionsWithJoins) (*CountResult[T], error) {
+	ctx, cancel := withDefaultTimeout(ctx)
+	defer cancel()
+
 	var result CountResult[T]
 
 	// Build count query
-	countQuery := buildCountQueryWithJoins(tableName, options)
-
-	args := []interface{}{}
-	if options != nil && options.WhereArgs != nil {
-		args = options.WhereArgs
+	countQuery, countArgs, err := buildCountQueryWithJoins(tableName, options)
+	if err != nil {
+		return nil, err
 	}
 
-	err := db.QueryRow(countQuery, args...).Scan(&result.Count)
+	err = runQueryRowContext(ctx, db, countQuery, countArgs...).Scan(&result.Count)
 	if err != nil {
 		return nil, fmt.Errorf("failed to count records: %w", err)
 	}
 
 	// Build select query
-	selectQuery := buildJoinQuery(tableName, options)
-	rows, err := db.Query(selectQuery, args...)
+	selectQuery, args, err := buildJoinQuery(tableName, options)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := runQueryContext(ctx, db, selectQuery, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query records: %w", err)
 	}
@@ -84,14 +127,42 @@ func FindAllAndCountWithJoins[T any](db *sql.DB, tableName string, options *Quer
 	return &result, nil
 }
 
+// CountWithJoins runs only the count query with joins
+func CountWithJoins(db Querier, tableName string, options *QueryOptionsWithJoins) (int, error) {
+	return CountWithJoinsContext(context.Background(), db, tableName, options)
+}
+
+// CountWithJoinsContext is like CountWithJoins but runs under ctx
+func CountWithJoinsContext(ctx context.Context, db Querier, tableName string, options *QueryOptionsWithJoins) (int, error) {
+	ctx, cancel := withDefaultTimeout(ctx)
+	defer cancel()
+
+	query, args, err := buildCountQueryWithJoins(tableName, options)
+	if err != nil {
+		return 0, err
+	}
+
+	var count int
+	if err := runQueryRowContext(ctx, db, query, args...).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count records: %w", err)
+	}
+
+	return count, nil
+}
+
 // FindOneWithJoins finds a single record with joins
-func FindOneWithJoins[T any](db *sql.DB, tableName string, options *QueryOptionsWithJoins) (*T, error) {
+func FindOneWithJoins[T any](db Querier, tableName string, options *QueryOptionsWithJoins) (*T, error) {
+	return FindOneWithJoinsContext[T](context.Background(), db, tableName, options)
+}
+
+// FindOneWithJoinsContext is like FindOneWithJoins but runs under ctx
+func FindOneWithJoinsContext[T any](ctx context.Context, db Querier, tableName string, options *QueryOptionsWithJoins) (*T, error) {
 	if options == nil {
 		options = &QueryOptionsWithJoins{}
 	}
 	options.Limit = 1
 
-	records, err := FindAllWithJoins[T](db, tableName, options)
+	records, err := FindAllWithJoinsContext[T](ctx, db, tableName, options)
 	if err != nil {
 		return nil, err
 	}
@@ -102,62 +173,209 @@ func FindOneWithJoins[T any](db *sql.DB, tableName string, options *QueryOptions
 	return &records[0], nil
 }
 
-// buildJoinQuery constructs a SELECT query with joins
-func buildJoinQuery(tableName string, options *QueryOptionsWithJoins) string {
+// buildJoinQuery constructs a SELECT query with joins and returns it with its args
+func buildJoinQuery(tableName string, options *QueryOptionsWithJoins) (string, []interface{}, error) {
 	selectClause := "*"
 	if options != nil && options.Select != "" {
 		selectClause = options.Select
 	}
 
-	query := fmt.Sprintf("SELECT %s FROM %s", selectClause, tableName)
+	with, args, err := buildWithClause(options)
+	if err != nil {
+		return "", nil, err
+	}
 
-	// Add joins
-	if options != nil && len(options.Joins) > 0 {
-		for _, join := range options.Joins {
-			query += fmt.Sprintf(" %s %s ON %s", join.Type, join.Table, join.Condition)
-		}
+	from, fromArgs, err := buildFromClause(tableName, options)
+	if err != nil {
+		return "", nil, err
 	}
+	args = append(args, fromArgs...)
+
+	query := fmt.Sprintf("%s%s %s FROM %s", with, selectKeyword(options != nil && options.Distinct), selectClause, from)
 
 	// Add WHERE clause
-	if options != nil && options.Where != "" {
-		query += " WHERE " + options.Where
-	}
+	where, whereArgs := buildJoinWhereClause(options)
+	query += where
+	args = append(args, whereArgs...)
 
 	// Add ORDER BY
-	if options != nil && options.OrderBy != "" {
-		query += " ORDER BY " + options.OrderBy
+	if options != nil {
+		orderBy, err := renderOrderBy(options.OrderBy, options.Order)
+		if err != nil {
+			return "", nil, err
+		}
+
+		if orderBy != "" {
+			query += " ORDER BY " + orderBy
+		}
+	}
+
+	// Add LIMIT and OFFSET
+	if options != nil {
+		limitOffset, err := buildLimitOffset(options.Limit, options.Offset)
+		if err != nil {
+			return "", nil, err
+		}
+		query += limitOffset
 	}
 
-	// Add LIMIT
-	if options != nil && options.Limit > 0 {
-		query += fmt.Sprintf(" LIMIT %d", options.Limit)
+	return query, args, nil
+}
+
+// buildCountQueryWithJoins constructs a COUNT query with joins and returns it with its args
+func buildCountQueryWithJoins(tableName string, options *QueryOptionsWithJoins) (string, []interface{}, error) {
+	with, args, err := buildWithClause(options)
+	if err != nil {
+		return "", nil, err
 	}
 
-	// Add OFFSET
-	if options != nil && options.Offset > 0 {
-		query += fmt.Sprintf(" OFFSET %d", options.Offset)
+	from, fromArgs, err := buildFromClause(tableName, options)
+	if err != nil {
+		return "", nil, err
 	}
+	args = append(args, fromArgs...)
 
-	return query
+	where, whereArgs := buildJoinWhereClause(options)
+	args = append(args, whereArgs...)
+
+	if options != nil && options.CountDistinct != "" {
+		column, err := validateIdent(options.CountDistinct)
+		if err != nil {
+			return "", nil, err
+		}
+
+		return fmt.Sprintf("%sSELECT COUNT(DISTINCT %s) FROM %s%s", with, column, from, where), args, nil
+	}
+
+	// Distinct rows have to be counted over the deduplicated result set
+	if options != nil && options.Distinct {
+		selectClause := "*"
+		if options.Select != "" {
+			selectClause = options.Select
+		}
+
+		query := fmt.Sprintf("SELECT DISTINCT %s FROM %s%s", selectClause, from, where)
+
+		return fmt.Sprintf("%sSELECT COUNT(*) FROM (%s) AS distinct_rows", with, query), args, nil
+	}
+
+	return fmt.Sprintf("%sSELECT COUNT(*) FROM %s%s", with, from, where), args, nil
 }
 
-// buildCountQueryWithJoins constructs a COUNT query with joins
-func buildCountQueryWithJoins(tableName string, options *QueryOptionsWithJoins) string {
-	query := fmt.Sprintf("SELECT COUNT(*) FROM %s", tableName)
+// buildWithClause renders the WITH prefix (including its trailing space) and
+// returns the args of the CTEs, which come before any of the main query's
+func buildWithClause(options *QueryOptionsWithJoins) (string, []interface{}, error) {
+	if options == nil || len(options.With) == 0 {
+		return "", nil, nil
+	}
+
+	var parts []string
+	var args []interface{}
+
+	for _, cte := range options.With {
+		name, err := validateIdent(cte.Name)
+		if err != nil {
+			return "", nil, err
+		}
+
+		sub := cte.Subquery
+		if cte.Builder != nil {
+			sub, err = cte.Builder.AsSubquery()
+			if err != nil {
+				return "", nil, fmt.Errorf("failed to build CTE %s: %w", cte.Name, err)
+			}
+		}
+		if sub == nil {
+			return "", nil, fmt.Errorf("CTE %s has no query", cte.Name)
+		}
+
+		parts = append(parts, fmt.Sprintf("%s AS (%s)", name, sub.Query))
+		args = append(args, sub.Args...)
+	}
+
+	keyword := "WITH "
+	if options.Recursive {
+		keyword = "WITH RECURSIVE "
+	}
+
+	return keyword + strings.Join(parts, ", ") + " ", args, nil
+}
+
+// buildJoinWhereClause renders the WHERE clause, expanding subquery args
+func buildJoinWhereClause(options *QueryOptionsWithJoins) (string, []interface{}) {
+	if options == nil || options.Where == "" {
+		return "", nil
+	}
+
+	where, args := expandSubqueries(options.Where, options.WhereArgs)
+	return " WHERE " + where, args
+}
+
+// buildFromClause renders the base table (or derived table) and its joins,
+// including any aliases, and returns the args of a derived table
+func buildFromClause(tableName string, options *QueryOptionsWithJoins) (string, []interface{}, error) {
+	var from string
+	var args []interface{}
+
+	if options != nil && options.From != nil {
+		if options.Alias == "" {
+			return "", nil, fmt.Errorf("a subquery in FROM requires an alias")
+		}
+
+		alias, err := validateIdent(options.Alias)
+		if err != nil {
+			return "", nil, err
+		}
+
+		from = fmt.Sprintf("(%s) %s", options.From.Query, alias)
+		args = append(args, options.From.Args...)
+	} else {
+		alias := ""
+		if options != nil {
+			alias = options.Alias
+		}
+
+		table, err := tableWithAlias(tableName, alias)
+		if err != nil {
+			return "", nil, err
+		}
+		from = table
+	}
 
 	// Add joins
 	if options != nil && len(options.Joins) > 0 {
 		for _, join := range options.Joins {
-			query += fmt.Sprintf(" %s %s ON %s", join.Type, join.Table, join.Condition)
+			table, err := tableWithAlias(join.Table, join.Alias)
+			if err != nil {
+				return "", nil, err
+			}
+			from += fmt.Sprintf(" %s %s", join.Type, table)
+			if join.Condition != "" {
+				from += " ON " + join.Condition
+			}
 		}
 	}
 
-	// Add WHERE clause
-	if options != nil && options.Where != "" {
-		query += " WHERE " + options.Where
+	return from, args, nil
+}
+
+// tableWithAlias validates a table name and optional alias and renders "table alias"
+func tableWithAlias(tableName, alias string) (string, error) {
+	table, err := validateIdent(tableName)
+	if err != nil {
+		return "", err
 	}
 
-	return query
+	if alias == "" {
+		return table, nil
+	}
+
+	alias, err = validateIdent(alias)
+	if err != nil {
+		return "", err
+	}
+
+	return table + " " + alias, nil
 }
 
 // Helper functions for building joins programmatically
@@ -198,6 +416,14 @@ func NewFullJoin(table, condition string) JoinClause {
 	}
 }
 
+// NewCrossJoin creates a CROSS JOIN clause, which takes no condition
+func NewCrossJoin(table string) JoinClause {
+	return JoinClause{
+		Type:  CrossJoin,
+		Table: table,
+	}
+}
+
 // JoinBuilder provides a fluent interface for building complex joins
 type JoinBuilder struct {
 	tableName string
@@ -214,6 +440,29 @@ func NewJoinBuilder(tableName string) *JoinBuilder {
 	}
 }
 
+// Alias sets the alias of the base table
+func (jb *JoinBuilder) Alias(alias string) *JoinBuilder {
+	jb.options.Alias = alias
+	return jb
+}
+
+// Join adds a prebuilt join clause, e.g. NewInnerJoin("orders", "o.userId = u.id").As("o")
+func (jb *JoinBuilder) Join(clause JoinClause) *JoinBuilder {
+	jb.options.Joins = append(jb.options.Joins, clause)
+	return jb
+}
+
+// NewJoinBuilderFromSubquery creates a JoinBuilder selecting from a derived table
+func NewJoinBuilderFromSubquery(subquery *Subquery, alias string) *JoinBuilder {
+	return &JoinBuilder{
+		options: &QueryOptionsWithJoins{
+			From:  subquery,
+			Alias: alias,
+			Joins: []JoinClause{},
+		},
+	}
+}
+
 // InnerJoin adds an INNER JOIN
 func (jb *JoinBuilder) InnerJoin(table, condition string) *JoinBuilder {
 	jb.options.Joins = append(jb.options.Joins, NewInnerJoin(table, condition))
@@ -238,12 +487,50 @@ func (jb *JoinBuilder) FullJoin(table, condition string) *JoinBuilder {
 	return jb
 }
 
+// CrossJoin adds a CROSS JOIN
+func (jb *JoinBuilder) CrossJoin(table string) *JoinBuilder {
+	jb.options.Joins = append(jb.options.Joins, NewCrossJoin(table))
+	return jb
+}
+
+// With adds a common table expression built from another builder
+func (jb *JoinBuilder) With(name string, subquery *JoinBuilder) *JoinBuilder {
+	jb.options.With = append(jb.options.With, CTE{Name: name, Builder: subquery})
+	return jb
+}
+
+// WithSubquery adds a common table expression from raw SQL, e.g. the
+// "anchor UNION ALL recursive step" body of a recursive CTE
+func (jb *JoinBuilder) WithSubquery(name string, subquery *Subquery) *JoinBuilder {
+	jb.options.With = append(jb.options.With, CTE{Name: name, Subquery: subquery})
+	return jb
+}
+
+// Recursive emits WITH RECURSIVE so CTEs may reference themselves
+func (jb *JoinBuilder) Recursive() *JoinBuilder {
+	jb.options.Recursive = true
+	return jb
+}
+
 // Select sets custom SELECT clause
 func (jb *JoinBuilder) Select(fields string) *JoinBuilder {
 	jb.options.Select = fields
 	return jb
 }
 
+// Distinct makes the query return only distinct rows
+func (jb *JoinBuilder) Distinct() *JoinBuilder {
+	jb.options.Distinct = true
+	return jb
+}
+
+// CountDistinct makes the count query count distinct values of column, e.g.
+// the base table's id when a join fans out
+func (jb *JoinBuilder) CountDistinct(column string) *JoinBuilder {
+	jb.options.CountDistinct = column
+	return jb
+}
+
 // Where sets the WHERE clause
 func (jb *JoinBuilder) Where(condition string, args ...interface{}) *JoinBuilder {
 	jb.options.Where = condition
@@ -257,6 +544,12 @@ func (jb *JoinBuilder) OrderBy(orderBy string) *JoinBuilder {
 	return jb
 }
 
+// Order appends validated ORDER BY terms, e.g. Order(Asc("name"), Desc("createdAt"))
+func (jb *JoinBuilder) Order(clauses ...OrderByClause) *JoinBuilder {
+	jb.options.Order = append(jb.options.Order, clauses...)
+	return jb
+}
+
 // Limit sets the LIMIT
 func (jb *JoinBuilder) Limit(limit int) *JoinBuilder {
 	jb.options.Limit = limit
@@ -275,8 +568,20 @@ func (jb *JoinBuilder) Build() *QueryOptionsWithJoins {
 }
 
 // GetQuery returns the built SQL query string (useful for debugging)
-func (jb *JoinBuilder) GetQuery() string {
-	return buildJoinQuery(jb.tableName, jb.options)
+func (jb *JoinBuilder) GetQuery() (string, error) {
+	query, _, err := buildJoinQuery(jb.tableName, jb.options)
+	return query, err
+}
+
+// AsSubquery builds the query so it can be used as a WHERE argument or as the
+// base table of another builder
+func (jb *JoinBuilder) AsSubquery() (*Subquery, error) {
+	query, args, err := buildJoinQuery(jb.tableName, jb.options)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Subquery{Query: query, Args: args}, nil
 }
 
 // GetTableName returns the base table name
@@ -290,16 +595,16 @@ func (jb *JoinBuilder) GetOptions() *QueryOptionsWithJoins {
 }
 
 // Execute executes a join builder and returns results
-func Execute[T any](db *sql.DB, builder *JoinBuilder) ([]T, error) {
+func Execute[T any](db Querier, builder *JoinBuilder) ([]T, error) {
 	return FindAllWithJoins[T](db, builder.GetTableName(), builder.GetOptions())
 }
 
 // ExecuteOne executes a join builder and returns a single result
-func ExecuteOne[T any](db *sql.DB, builder *JoinBuilder) (*T, error) {
+func ExecuteOne[T any](db Querier, builder *JoinBuilder) (*T, error) {
 	return FindOneWithJoins[T](db, builder.GetTableName(), builder.GetOptions())
 }
 
 // ExecuteWithCount executes a join builder with count
-func ExecuteWithCount[T any](db *sql.DB, builder *JoinBuilder) (*CountResult[T], error) {
+func ExecuteWithCount[T any](db Querier, builder *JoinBuilder) (*CountResult[T], error) {
 	return FindAllAndCountWithJoins[T](db, builder.GetTableName(), builder.GetOptions())
 }
@@ -0,0 +1,172 @@
+package db
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSubqueries(t *testing.T) {
+	t.Run("should inline a subquery in WHERE and interleave its args", func(t *testing.T) {
+		sub, err := NewJoinBuilder("order_items").
+			Select("productId").
+			Where("quantity > ?", 5).
+			AsSubquery()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		query, args, err := buildJoinQuery("products", NewJoinBuilder("products").
+			Where("price > ? AND id NOT IN ? AND quantity < ?", 10, sub, 3).
+			Build())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected := "SELECT * FROM products WHERE price > ? AND id NOT IN (SELECT productId FROM order_items WHERE quantity > ?) AND quantity < ?"
+		if query != expected {
+			t.Errorf("expected %q, got %q", expected, query)
+		}
+
+		if !reflect.DeepEqual(args, []interface{}{10, 5, 3}) {
+			t.Errorf("expected args [10 5 3], got %v", args)
+		}
+	})
+
+	t.Run("should select from a derived table", func(t *testing.T) {
+		sub, err := NewJoinBuilder("products").Where("quantity > ?", 0).AsSubquery()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		builder := NewJoinBuilderFromSubquery(sub, "p").Where("p.price < ?", 100)
+		query, args, err := buildJoinQuery(builder.GetTableName(), builder.GetOptions())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected := "SELECT * FROM (SELECT * FROM products WHERE quantity > ?) p WHERE p.price < ?"
+		if query != expected {
+			t.Errorf("expected %q, got %q", expected, query)
+		}
+
+		if !reflect.DeepEqual(args, []interface{}{0, 100}) {
+			t.Errorf("expected args [0 100], got %v", args)
+		}
+	})
+}
+
+func TestSelectColumns(t *testing.T) {
+	t.Run("should resolve output column names", func(t *testing.T) {
+		columns := selectColumns("p.id, p.name AS productName, COUNT(o.id, 1) AS orders")
+		expected := []string{"id", "productName", "orders"}
+
+		if !reflect.DeepEqual(columns, expected) {
+			t.Errorf("expected %v, got %v", expected, columns)
+		}
+	})
+}
+
+func TestJoinBuilderQueries(t *testing.T) {
+	t.Run("should build a self-join with aliases", func(t *testing.T) {
+		query, err := NewJoinBuilder("users").
+			Alias("u1").
+			Join(NewInnerJoin("users", "u1.managerId = u2.id").As("u2")).
+			Select("u1.id, u2.firstName AS managerName").
+			GetQuery()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected := "SELECT u1.id, u2.firstName AS managerName FROM users u1 INNER JOIN users u2 ON u1.managerId = u2.id"
+		if query != expected {
+			t.Errorf("expected %q, got %q", expected, query)
+		}
+	})
+
+	t.Run("should build a cross join without a condition", func(t *testing.T) {
+		query, err := NewJoinBuilder("products").CrossJoin("users").GetQuery()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected := "SELECT * FROM products CROSS JOIN users"
+		if query != expected {
+			t.Errorf("expected %q, got %q", expected, query)
+		}
+	})
+	t.Run("should count distinct base rows across a one-to-many join", func(t *testing.T) {
+		builder := NewJoinBuilder("users").
+			Alias("u").
+			Join(NewLeftJoin("orders", "o.userId = u.id").As("o")).
+			Where("o.total > ?", 10).
+			CountDistinct("u.id")
+
+		query, args, err := buildCountQueryWithJoins(builder.GetTableName(), builder.GetOptions())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected := "SELECT COUNT(DISTINCT u.id) FROM users u LEFT JOIN orders o ON o.userId = u.id WHERE o.total > ?"
+		if query != expected {
+			t.Errorf("expected %q, got %q", expected, query)
+		}
+
+		if !reflect.DeepEqual(args, []interface{}{10}) {
+			t.Errorf("expected args [10], got %v", args)
+		}
+	})
+
+	t.Run("should reject an invalid count column", func(t *testing.T) {
+		builder := NewJoinBuilder("users").CountDistinct("u.id; DROP TABLE users")
+
+		if _, _, err := buildCountQueryWithJoins(builder.GetTableName(), builder.GetOptions()); err == nil {
+			t.Error("expected an error for an invalid count column")
+		}
+	})
+	t.Run("should prepend CTEs and put their args first", func(t *testing.T) {
+		bigSpenders := NewJoinBuilder("orders").
+			Select("userId").
+			Where("total > ?", 100)
+		recent := NewJoinBuilder("orders").
+			Select("userId").
+			Where("createdAt > ?", "2025-01-01")
+
+		query, args, err := buildJoinQuery("users", NewJoinBuilder("users").
+			With("big_spenders", bigSpenders).
+			With("recent", recent).
+			InnerJoin("big_spenders", "big_spenders.userId = users.id").
+			InnerJoin("recent", "recent.userId = users.id").
+			Where("users.id > ?", 5).
+			Build())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected := "WITH big_spenders AS (SELECT userId FROM orders WHERE total > ?), recent AS (SELECT userId FROM orders WHERE createdAt > ?) " +
+			"SELECT * FROM users INNER JOIN big_spenders ON big_spenders.userId = users.id INNER JOIN recent ON recent.userId = users.id WHERE users.id > ?"
+		if query != expected {
+			t.Errorf("expected %q, got %q", expected, query)
+		}
+
+		if !reflect.DeepEqual(args, []interface{}{100, "2025-01-01", 5}) {
+			t.Errorf("expected args [100 2025-01-01 5], got %v", args)
+		}
+	})
+
+	t.Run("should emit WITH RECURSIVE", func(t *testing.T) {
+		query, err := NewJoinBuilder("tree").
+			Recursive().
+			WithSubquery("tree", &Subquery{
+				Query: "SELECT id, parentId FROM categories WHERE parentId IS NULL UNION ALL SELECT c.id, c.parentId FROM categories c INNER JOIN tree t ON c.parentId = t.id",
+			}).
+			GetQuery()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected := "WITH RECURSIVE tree AS (SELECT id, parentId FROM categories WHERE parentId IS NULL UNION ALL SELECT c.id, c.parentId FROM categories c INNER JOIN tree t ON c.parentId = t.id) SELECT * FROM tree"
+		if query != expected {
+			t.Errorf("expected %q, got %q", expected, query)
+		}
+	})
+}
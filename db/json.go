@@ -0,0 +1,86 @@
+package db
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+var valuerType = reflect.TypeOf((*driver.Valuer)(nil)).Elem()
+
+// isJSONField reports whether a field is stored as a JSON column: either its
+// db tag has the json option (db:"metadata,json") or it is a map, a slice
+// other than []byte, or a struct the driver can't bind or scan itself
+func isJSONField(sf structField) bool {
+	for _, option := range strings.Split(sf.field.Tag.Get("db"), ",")[1:] {
+		if option == "json" {
+			return true
+		}
+	}
+
+	t := sf.field.Type
+	switch t.Kind() {
+	case reflect.Map:
+		return true
+	case reflect.Slice:
+		return t.Elem().Kind() != reflect.Uint8
+	case reflect.Struct:
+		if t == timeType || t.Implements(valuerType) || reflect.PointerTo(t).Implements(scannerType) {
+			return false
+		}
+		return true
+	}
+
+	return false
+}
+
+// jsonValue binds a Go value as its JSON encoding, or NULL for nil maps,
+// slices and pointers
+type jsonValue struct {
+	value reflect.Value
+}
+
+func (j jsonValue) Value() (driver.Value, error) {
+	switch j.value.Kind() {
+	case reflect.Map, reflect.Slice, reflect.Ptr, reflect.Interface:
+		if j.value.IsNil() {
+			return nil, nil
+		}
+	}
+
+	data, err := json.Marshal(j.value.Interface())
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal JSON column: %w", err)
+	}
+
+	return string(data), nil
+}
+
+// jsonColumn scans a JSON column straight into a struct field. NULL leaves
+// the field at its zero value.
+type jsonColumn struct {
+	field reflect.Value
+}
+
+func (j *jsonColumn) Scan(src interface{}) error {
+	var data []byte
+	switch value := src.(type) {
+	case nil:
+		j.field.Set(reflect.Zero(j.field.Type()))
+		return nil
+	case []byte:
+		data = value
+	case string:
+		data = []byte(value)
+	default:
+		return fmt.Errorf("cannot scan %T into a JSON column", src)
+	}
+
+	if err := json.Unmarshal(data, j.field.Addr().Interface()); err != nil {
+		return fmt.Errorf("failed to unmarshal JSON column: %w", err)
+	}
+
+	return nil
+}
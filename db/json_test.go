@@ -0,0 +1,70 @@
+package db
+
+import (
+	"database/sql/driver"
+	"testing"
+)
+
+type productAttributes struct {
+	Color string `json:"color"`
+	Sizes []int  `json:"sizes"`
+}
+
+type jsonRecord struct {
+	ID         int               `db:"id" insert:"-"`
+	Attributes productAttributes `db:"attributes"`
+	Tags       []string          `db:"tags"`
+	Metadata   map[string]any    `db:"metadata,json"`
+}
+
+func TestJSONColumns(t *testing.T) {
+	columns := []string{"id", "attributes", "tags", "metadata"}
+
+	t.Run("should marshal struct, slice and map fields on insert", func(t *testing.T) {
+		_, _, values := buildInsertData(jsonRecord{
+			Attributes: productAttributes{Color: "red", Sizes: []int{1, 2}},
+			Tags:       []string{"sale"},
+		})
+
+		var args []driver.Value
+		for _, value := range values {
+			arg, err := value.(driver.Valuer).Value()
+			if err != nil {
+				t.Fatal(err)
+			}
+			args = append(args, arg)
+		}
+
+		if args[0] != `{"color":"red","sizes":[1,2]}` {
+			t.Errorf("unexpected attributes argument %v", args[0])
+		}
+		if args[1] != `["sale"]` {
+			t.Errorf("unexpected tags argument %v", args[1])
+		}
+		if args[2] != nil {
+			t.Errorf("expected a nil map to be bound as NULL, got %v", args[2])
+		}
+	})
+
+	t.Run("should unmarshal JSON columns and leave NULL as zero values", func(t *testing.T) {
+		conn, _ := newFakeDB(t, columns,
+			[]driver.Value{int64(1), []byte(`{"color":"blue","sizes":[3]}`), nil, `{"featured":true}`},
+		)
+
+		records, err := FindAll[jsonRecord](conn, "products", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		r := records[0]
+		if r.Attributes.Color != "blue" || len(r.Attributes.Sizes) != 1 || r.Attributes.Sizes[0] != 3 {
+			t.Errorf("unexpected attributes %+v", r.Attributes)
+		}
+		if r.Tags != nil {
+			t.Errorf("expected nil tags for a NULL column, got %v", r.Tags)
+		}
+		if r.Metadata["featured"] != true {
+			t.Errorf("unexpected metadata %v", r.Metadata)
+		}
+	})
+}
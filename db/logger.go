@@ -0,0 +1,80 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"time"
+)
+
+// Logger, when set, is called with every query the package executes along with
+// its arguments and how long it took. It is nil (disabled) by default.
+var Logger func(query string, args []interface{}, duration time.Duration)
+
+// SlowQueryThreshold, when positive, logs every query taking at least that
+// long as a warning, whether Logger is set or not. The args are left out
+// since they can hold credentials.
+var SlowQueryThreshold time.Duration
+
+// Querier is what the package runs queries against. Both *sql.DB and *sql.Tx
+// satisfy it, so the same helpers work inside a transaction, and tests can
+// pass their own implementation.
+type Querier interface {
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+func logQuery(query string, args []interface{}, start time.Time) {
+	duration := time.Since(start)
+
+	if Logger != nil {
+		Logger(query, args, duration)
+	}
+
+	if SlowQueryThreshold > 0 && duration >= SlowQueryThreshold {
+		slog.Warn("slow query", "query", query, "duration", duration)
+	}
+}
+
+func runQuery(q Querier, query string, args ...interface{}) (*sql.Rows, error) {
+	return runQueryContext(context.Background(), q, query, args...)
+}
+
+func runQueryRow(q Querier, query string, args ...interface{}) *sql.Row {
+	return runQueryRowContext(context.Background(), q, query, args...)
+}
+
+func runExec(q Querier, query string, args ...interface{}) (sql.Result, error) {
+	return runExecContext(context.Background(), q, query, args...)
+}
+
+func runQueryContext(ctx context.Context, q Querier, query string, args ...interface{}) (*sql.Rows, error) {
+	defer logQuery(query, args, time.Now())
+	if stmt, release := cachedStmt(q, query); stmt != nil {
+		defer release()
+		return stmt.QueryContext(ctx, args...)
+	}
+	return q.QueryContext(ctx, query, args...)
+}
+
+func runQueryRowContext(ctx context.Context, q Querier, query string, args ...interface{}) *sql.Row {
+	defer logQuery(query, args, time.Now())
+	if stmt, release := cachedStmt(q, query); stmt != nil {
+		defer release()
+		return stmt.QueryRowContext(ctx, args...)
+	}
+	return q.QueryRowContext(ctx, query, args...)
+}
+
+func runExecContext(ctx context.Context, q Querier, query string, args ...interface{}) (sql.Result, error) {
+	defer logQuery(query, args, time.Now())
+	if stmt, release := cachedStmt(q, query); stmt != nil {
+		defer release()
+		return stmt.ExecContext(ctx, args...)
+	}
+	return q.ExecContext(ctx, query, args...)
+}
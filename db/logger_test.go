@@ -0,0 +1,45 @@
+package db
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSlowQueryThreshold(t *testing.T) {
+	var buf bytes.Buffer
+	defaultLogger := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(&buf, nil)))
+	defer slog.SetDefault(defaultLogger)
+	defer func() { SlowQueryThreshold = 0 }()
+
+	t.Run("should warn about a query over the threshold", func(t *testing.T) {
+		buf.Reset()
+		SlowQueryThreshold = time.Nanosecond
+		conn, _ := newFakeDB(t, []string{"id", "name"})
+
+		if _, err := FindAll[nullableRecord](conn, "products", nil); err != nil {
+			t.Fatal(err)
+		}
+
+		if !strings.Contains(buf.String(), "level=WARN msg=\"slow query\" query=\"SELECT * FROM products\"") {
+			t.Errorf("expected a slow query warning, got %q", buf.String())
+		}
+	})
+
+	t.Run("should stay quiet when disabled", func(t *testing.T) {
+		buf.Reset()
+		SlowQueryThreshold = 0
+		conn, _ := newFakeDB(t, []string{"id", "name"})
+
+		if _, err := FindAll[nullableRecord](conn, "products", nil); err != nil {
+			t.Fatal(err)
+		}
+
+		if buf.Len() != 0 {
+			t.Errorf("expected no log output, got %q", buf.String())
+		}
+	})
+}
@@ -0,0 +1,200 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// MigrationsTable tracks the applied version. It uses the same layout as
+// golang-migrate (one row of version and dirty), so both can run against
+// the same database.
+const MigrationsTable = "schema_migrations"
+
+// Migration is one version read from a migrations directory
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string // file name of the up script
+	Down    string // file name of the down script, empty when there is none
+}
+
+// LoadMigrations reads "<version>_<name>.up.sql" and ".down.sql" files from
+// the root of fsys, ordered by version
+func LoadMigrations(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	byVersion := map[int64]*Migration{}
+	for _, entry := range entries {
+		if entry.IsDir() || path.Ext(entry.Name()) != ".sql" {
+			continue
+		}
+
+		base := strings.TrimSuffix(entry.Name(), ".sql")
+		direction := path.Ext(base)
+		if direction != ".up" && direction != ".down" {
+			return nil, fmt.Errorf("migration %q must end in .up.sql or .down.sql", entry.Name())
+		}
+
+		versionPart, name, _ := strings.Cut(strings.TrimSuffix(base, direction), "_")
+		version, err := strconv.ParseInt(versionPart, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("migration %q does not start with a version", entry.Name())
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: name}
+			byVersion[version] = m
+		}
+
+		if direction == ".up" {
+			m.Up = entry.Name()
+		} else {
+			m.Down = entry.Name()
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migration %d has no up script", m.Version)
+		}
+		migrations = append(migrations, *m)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+
+	return migrations, nil
+}
+
+// Migrate applies every migration of fsys newer than the current version, in
+// order. Each script must hold a single statement.
+func Migrate(db *sql.DB, fsys fs.FS) error {
+	migrations, err := LoadMigrations(fsys)
+	if err != nil {
+		return err
+	}
+
+	current, err := migrationVersion(db)
+	if err != nil {
+		return err
+	}
+
+	for _, m := range migrations {
+		if m.Version <= current {
+			continue
+		}
+
+		if err := runMigration(db, fsys, m.Up, m.Version, m.Version); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// MigrateDown rolls back the latest applied migration
+func MigrateDown(db *sql.DB, fsys fs.FS) error {
+	migrations, err := LoadMigrations(fsys)
+	if err != nil {
+		return err
+	}
+
+	current, err := migrationVersion(db)
+	if err != nil {
+		return err
+	}
+
+	for i := len(migrations) - 1; i >= 0; i-- {
+		m := migrations[i]
+		if m.Version != current {
+			continue
+		}
+
+		if m.Down == "" {
+			return fmt.Errorf("migration %d has no down script", m.Version)
+		}
+
+		var previous int64
+		if i > 0 {
+			previous = migrations[i-1].Version
+		}
+
+		return runMigration(db, fsys, m.Down, m.Version, previous)
+	}
+
+	if current == 0 {
+		return nil
+	}
+
+	return fmt.Errorf("applied migration %d not found", current)
+}
+
+// migrationVersion creates the tracking table when needed and returns the
+// applied version, 0 on a clean database
+func migrationVersion(db *sql.DB) (int64, error) {
+	_, err := runExec(db, "CREATE TABLE IF NOT EXISTS "+MigrationsTable+" (version BIGINT NOT NULL PRIMARY KEY, dirty BOOLEAN NOT NULL)")
+	if err != nil {
+		return 0, fmt.Errorf("failed to create %s: %w", MigrationsTable, err)
+	}
+
+	var version int64
+	var dirty bool
+	err = runQueryRow(db, "SELECT version, dirty FROM "+MigrationsTable+" LIMIT 1").Scan(&version, &dirty)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to read the migration version: %w", err)
+	}
+
+	if dirty {
+		return 0, fmt.Errorf("database is dirty at migration %d, fix it by hand and reset the version", version)
+	}
+
+	return version, nil
+}
+
+// runMigration runs one script. The version is marked dirty while it runs,
+// since DDL can't be rolled back on MySQL, and set to next afterwards.
+func runMigration(db *sql.DB, fsys fs.FS, file string, version, next int64) error {
+	script, err := fs.ReadFile(fsys, file)
+	if err != nil {
+		return fmt.Errorf("failed to read migration %s: %w", file, err)
+	}
+
+	if err := setMigrationVersion(db, version, true); err != nil {
+		return err
+	}
+
+	if _, err := runExec(db, string(script)); err != nil {
+		return fmt.Errorf("migration %s failed: %w", file, err)
+	}
+
+	if next == 0 {
+		_, err := runExec(db, "DELETE FROM "+MigrationsTable)
+		return err
+	}
+
+	return setMigrationVersion(db, next, false)
+}
+
+func setMigrationVersion(db *sql.DB, version int64, dirty bool) error {
+	return WithTransaction(db, func(tx *sql.Tx) error {
+		if _, err := runExec(tx, "DELETE FROM "+MigrationsTable); err != nil {
+			return err
+		}
+
+		_, err := runExec(tx, "INSERT INTO "+MigrationsTable+" (version, dirty) VALUES (?, ?)", version, dirty)
+		return err
+	})
+}
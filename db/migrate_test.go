@@ -0,0 +1,77 @@
+package db
+
+import (
+	"database/sql/driver"
+	"testing"
+	"testing/fstest"
+)
+
+func TestMigrate(t *testing.T) {
+	fsys := fstest.MapFS{
+		"20250101000000_add-users.up.sql":      {Data: []byte("CREATE TABLE users (id INT)")},
+		"20250101000000_add-users.down.sql":    {Data: []byte("DROP TABLE users")},
+		"20250102000000_add-products.up.sql":   {Data: []byte("CREATE TABLE products (id INT)")},
+		"20250102000000_add-products.down.sql": {Data: []byte("DROP TABLE products")},
+	}
+
+	t.Run("should load migrations ordered by version", func(t *testing.T) {
+		migrations, err := LoadMigrations(fsys)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(migrations) != 2 || migrations[0].Name != "add-users" || migrations[1].Down != "20250102000000_add-products.down.sql" {
+			t.Errorf("unexpected migrations %+v", migrations)
+		}
+	})
+
+	t.Run("should only apply migrations newer than the current version", func(t *testing.T) {
+		conn, fake := newFakeDB(t, []string{"version", "dirty"}, []driver.Value{int64(20250101000000), false})
+
+		if err := Migrate(conn, fsys); err != nil {
+			t.Fatal(err)
+		}
+
+		applied := 0
+		for _, query := range fake.Queries {
+			switch query {
+			case "CREATE TABLE users (id INT)":
+				t.Error("applied an already applied migration")
+			case "CREATE TABLE products (id INT)":
+				applied++
+			}
+		}
+		if applied != 1 {
+			t.Errorf("expected the products migration to run once, ran %d times", applied)
+		}
+	})
+
+	t.Run("should refuse to run on a dirty database", func(t *testing.T) {
+		conn, _ := newFakeDB(t, []string{"version", "dirty"}, []driver.Value{int64(20250101000000), true})
+
+		if err := Migrate(conn, fsys); err == nil {
+			t.Error("expected an error")
+		}
+	})
+
+	t.Run("should roll back the latest migration", func(t *testing.T) {
+		conn, fake := newFakeDB(t, []string{"version", "dirty"}, []driver.Value{int64(20250102000000), false})
+
+		if err := MigrateDown(conn, fsys); err != nil {
+			t.Fatal(err)
+		}
+
+		found := false
+		for i, query := range fake.Queries {
+			if query == "DROP TABLE products" {
+				found = true
+			}
+			if query == "INSERT INTO schema_migrations (version, dirty) VALUES (?, ?)" && fake.Args[i][1] == false && fake.Args[i][0] != int64(20250101000000) {
+				t.Errorf("expected the version to go back to 20250101000000, got %v", fake.Args[i][0])
+			}
+		}
+		if !found {
+			t.Error("expected the down script to run")
+		}
+	})
+}
@@ -0,0 +1,80 @@
+package db
+
+import (
+	"fmt"
+	"strings"
+)
+
+// OrderByClause is a single, validated ORDER BY term
+type OrderByClause struct {
+	Column string `json:"column"`
+	Desc   bool   `json:"desc,omitempty"`
+}
+
+// Asc orders by column ascending
+func Asc(column string) OrderByClause {
+	return OrderByClause{Column: column}
+}
+
+// Desc orders by column descending
+func Desc(column string) OrderByClause {
+	return OrderByClause{Column: column, Desc: true}
+}
+
+// renderOrderBy renders the structured clauses, falling back to the raw
+// OrderBy string when none are given
+func renderOrderBy(raw string, clauses []OrderByClause) (string, error) {
+	if len(clauses) == 0 {
+		if raw == "" {
+			return "", nil
+		}
+		return buildOrderBy(raw)
+	}
+
+	parts := make([]string, 0, len(clauses))
+	for _, clause := range clauses {
+		column, err := validateIdent(clause.Column)
+		if err != nil {
+			return "", err
+		}
+
+		if clause.Desc {
+			parts = append(parts, column+" DESC")
+		} else {
+			parts = append(parts, column+" ASC")
+		}
+	}
+
+	return strings.Join(parts, ", "), nil
+}
+
+// buildOrderBy validates a raw ORDER BY list such as "name, createdAt DESC"
+// and returns it normalized
+func buildOrderBy(orderBy string) (string, error) {
+	var parts []string
+
+	for _, item := range strings.Split(orderBy, ",") {
+		fields := strings.Fields(item)
+		if len(fields) == 0 || len(fields) > 2 {
+			return "", fmt.Errorf("invalid order by clause: %q", orderBy)
+		}
+
+		column, err := validateIdent(fields[0])
+		if err != nil {
+			return "", err
+		}
+
+		if len(fields) == 1 {
+			parts = append(parts, column)
+			continue
+		}
+
+		direction := strings.ToUpper(fields[1])
+		if direction != "ASC" && direction != "DESC" {
+			return "", fmt.Errorf("invalid order by direction: %q", fields[1])
+		}
+		parts = append(parts, column+" "+direction)
+	}
+
+	return strings.Join(parts, ", "), nil
+}
@@ -0,0 +1,48 @@
+package db
+
+import "testing"
+
+func TestRenderOrderBy(t *testing.T) {
+	t.Run("should render structured clauses with directions", func(t *testing.T) {
+		orderBy, err := renderOrderBy("", []OrderByClause{Asc("name"), Desc("created_at")})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if orderBy != "name ASC, created_at DESC" {
+			t.Errorf("expected %q, got %q", "name ASC, created_at DESC", orderBy)
+		}
+	})
+
+	t.Run("should prefer structured clauses over the raw string", func(t *testing.T) {
+		orderBy, err := renderOrderBy("id", []OrderByClause{Desc("price")})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if orderBy != "price DESC" {
+			t.Errorf("expected %q, got %q", "price DESC", orderBy)
+		}
+	})
+
+	t.Run("should keep supporting the raw string", func(t *testing.T) {
+		orderBy, err := renderOrderBy("name, createdAt desc", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if orderBy != "name, createdAt DESC" {
+			t.Errorf("expected %q, got %q", "name, createdAt DESC", orderBy)
+		}
+	})
+
+	t.Run("should reject invalid column names", func(t *testing.T) {
+		if _, err := renderOrderBy("", []OrderByClause{Asc("name; DROP TABLE users")}); err == nil {
+			t.Error("expected an error for an invalid column")
+		}
+
+		if _, err := renderOrderBy("name ASC; DROP TABLE users", nil); err == nil {
+			t.Error("expected an error for an invalid raw order by")
+		}
+	})
+}
@@ -0,0 +1,113 @@
+package db
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// KeysetPage is one page of a keyset (cursor) paginated query
+type KeysetPage[T any] struct {
+	Data []T `json:"data"`
+	// NextCursor is the sort column value of the last row, or nil on the last page
+	NextCursor interface{} `json:"nextCursor"`
+}
+
+// FindPage returns up to size records ordered by column whose value is greater
+// than after (pass nil for the first page). Unlike OFFSET, the cost of a page
+// does not grow with how deep into the table it is.
+func FindPage[T any](db Querier, tableName, column string, after interface{}, size int, options *QueryOptions) (*KeysetPage[T], error) {
+	if size <= 0 {
+		return nil, fmt.Errorf("invalid page size: %d", size)
+	}
+
+	column, err := validateIdent(column)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := QueryOptions{}
+	if options != nil {
+		opts = *options
+	}
+
+	// Copy the args so the caller's slice is never appended to
+	opts.WhereArgs = append([]interface{}{}, opts.WhereArgs...)
+	if after != nil {
+		condition := column + " > ?"
+		if opts.Where != "" {
+			condition = "(" + opts.Where + ") AND " + condition
+		}
+		opts.Where = condition
+		opts.WhereArgs = append(opts.WhereArgs, after)
+	}
+
+	opts.OrderBy = ""
+	opts.Order = []OrderByClause{Asc(column)}
+	// Fetch one extra row to know whether another page exists
+	opts.Limit = size + 1
+	opts.Offset = 0
+
+	records, err := FindAll[T](db, tableName, &opts)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &KeysetPage[T]{Data: records}
+	if len(records) > size {
+		page.Data = records[:size]
+
+		cursor, err := columnValue(&page.Data[size-1], column)
+		if err != nil {
+			return nil, err
+		}
+		page.NextCursor = cursor
+	}
+
+	return page, nil
+}
+
+// columnValue returns the value of the field of record tagged with column
+func columnValue(record interface{}, column string) (interface{}, error) {
+	// A qualified column ("p.id") maps to the unqualified tag
+	if i := strings.LastIndex(column, "."); i >= 0 {
+		column = column[i+1:]
+	}
+
+	v := reflect.ValueOf(record).Elem()
+
+	for _, sf := range structFields(v) {
+		if strings.Split(sf.field.Tag.Get("db"), ",")[0] == column {
+			return sf.value.Interface(), nil
+		}
+	}
+
+	return nil, fmt.Errorf("no field tagged with column %q on %s", column, v.Type().Name())
+}
+
+// buildLimitOffset validates and renders the LIMIT and OFFSET clauses
+func buildLimitOffset(limit, offset int) (string, error) {
+	if limit < 0 {
+		return "", fmt.Errorf("invalid limit: %d", limit)
+	}
+
+	if offset < 0 {
+		return "", fmt.Errorf("invalid offset: %d", offset)
+	}
+
+	// OFFSET without LIMIT is not valid SQL in every dialect
+	if offset > 0 && limit == 0 {
+		return "", fmt.Errorf("offset %d requires a limit", offset)
+	}
+
+	var clause string
+	if limit > 0 {
+		clause += fmt.Sprintf(" LIMIT %d", limit)
+	}
+
+	if offset > 0 {
+		clause += fmt.Sprintf(" OFFSET %d", offset)
+	}
+
+	return clause, nil
+}
@@ -0,0 +1,44 @@
+package db
+
+import (
+	"database/sql"
+	"time"
+)
+
+// PoolConfig sizes the connection pool of a *sql.DB. Zero values keep the
+// database/sql default for that setting.
+type PoolConfig struct {
+	MaxOpenConns    int           // Upper bound on open connections (default unlimited)
+	MaxIdleConns    int           // Connections kept open while idle (default 2)
+	ConnMaxLifetime time.Duration // Recycle connections after this long (default never)
+	ConnMaxIdleTime time.Duration // Close connections idle for this long (default never)
+}
+
+// DefaultPoolConfig is a reasonable starting point for a small service: enough
+// connections for concurrent requests, all kept warm, and recycled well
+// before MySQL's wait_timeout closes them on the server side
+var DefaultPoolConfig = PoolConfig{
+	MaxOpenConns:    25,
+	MaxIdleConns:    25,
+	ConnMaxLifetime: 5 * time.Minute,
+	ConnMaxIdleTime: time.Minute,
+}
+
+// ConfigurePool applies cfg to the pool of db
+func ConfigurePool(db *sql.DB, cfg PoolConfig) {
+	if cfg.MaxOpenConns > 0 {
+		db.SetMaxOpenConns(cfg.MaxOpenConns)
+	}
+
+	if cfg.MaxIdleConns > 0 {
+		db.SetMaxIdleConns(cfg.MaxIdleConns)
+	}
+
+	if cfg.ConnMaxLifetime > 0 {
+		db.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	}
+
+	if cfg.ConnMaxIdleTime > 0 {
+		db.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
+	}
+}
@@ -0,0 +1,98 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// Raw runs arbitrary SQL and scans the rows into T, for queries the builders
+// can't express (window functions, CTEs, vendor-specific syntax). The query is
+// sent as-is, so only bind values through args.
+func Raw[T any](db Querier, query string, args ...interface{}) ([]T, error) {
+	return RawContext[T](context.Background(), db, query, args...)
+}
+
+// RawContext is like Raw but runs under ctx
+func RawContext[T any](ctx context.Context, db Querier, query string, args ...interface{}) ([]T, error) {
+	ctx, cancel := withDefaultTimeout(ctx)
+	defer cancel()
+
+	rows, err := runQueryContext(ctx, db, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to run raw query: %w", err)
+	}
+	defer rows.Close()
+
+	return scanRows[T](rows)
+}
+
+// RawOne runs arbitrary SQL and scans the first row into T, returning
+// sql.ErrNoRows when there is none
+func RawOne[T any](db Querier, query string, args ...interface{}) (*T, error) {
+	return RawOneContext[T](context.Background(), db, query, args...)
+}
+
+// RawOneContext is like RawOne but runs under ctx
+func RawOneContext[T any](ctx context.Context, db Querier, query string, args ...interface{}) (*T, error) {
+	records, err := RawContext[T](ctx, db, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(records) == 0 {
+		return nil, sql.ErrNoRows
+	}
+
+	return &records[0], nil
+}
+
+// FindAllMap runs arbitrary SQL and returns every row as a map of column name
+// to value, for results whose shape isn't known at compile time (ad hoc
+// reports, debug endpoints). []byte values are turned into strings, since the
+// MySQL driver returns most text and numeric columns that way.
+func FindAllMap(db Querier, query string, args ...interface{}) ([]map[string]interface{}, error) {
+	return FindAllMapContext(context.Background(), db, query, args...)
+}
+
+// FindAllMapContext is like FindAllMap but runs under ctx
+func FindAllMapContext(ctx context.Context, db Querier, query string, args ...interface{}) ([]map[string]interface{}, error) {
+	ctx, cancel := withDefaultTimeout(ctx)
+	defer cancel()
+
+	rows, err := runQueryContext(ctx, db, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to run raw query: %w", err)
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read columns: %w", err)
+	}
+
+	results := []map[string]interface{}{}
+	for rows.Next() {
+		values := make([]interface{}, len(columns))
+		pointers := make([]interface{}, len(columns))
+		for i := range values {
+			pointers[i] = &values[i]
+		}
+
+		if err := rows.Scan(pointers...); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+
+		row := make(map[string]interface{}, len(columns))
+		for i, column := range columns {
+			if b, ok := values[i].([]byte); ok {
+				row[column] = string(b)
+			} else {
+				row[column] = values[i]
+			}
+		}
+		results = append(results, row)
+	}
+
+	return results, rows.Err()
+}
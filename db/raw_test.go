@@ -0,0 +1,46 @@
+package db
+
+import (
+	"database/sql/driver"
+	"reflect"
+	"testing"
+)
+
+func TestFindAllMap(t *testing.T) {
+	t.Run("should return a map per row with text as strings", func(t *testing.T) {
+		conn, fake := newFakeDB(t, []string{"id", "name", "description"},
+			[]driver.Value{int64(1), []byte("chair"), nil},
+			[]driver.Value{int64(2), "table", []byte("oak")},
+		)
+
+		rows, err := FindAllMap(conn, "SELECT id, name, description FROM products WHERE price > ?", 5)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected := []map[string]interface{}{
+			{"id": int64(1), "name": "chair", "description": nil},
+			{"id": int64(2), "name": "table", "description": "oak"},
+		}
+		if !reflect.DeepEqual(rows, expected) {
+			t.Errorf("expected %v, got %v", expected, rows)
+		}
+
+		if len(fake.Args[0]) != 1 {
+			t.Errorf("expected the args to be bound, got %v", fake.Args[0])
+		}
+	})
+
+	t.Run("should return an empty slice without rows", func(t *testing.T) {
+		conn, _ := newFakeDB(t, []string{"id"})
+
+		rows, err := FindAllMap(conn, "SELECT id FROM products")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if rows == nil || len(rows) != 0 {
+			t.Errorf("expected an empty slice, got %v", rows)
+		}
+	})
+}
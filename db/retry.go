@@ -0,0 +1,65 @@
+package db
+
+import (
+	"database/sql/driver"
+	"errors"
+	"io"
+	"syscall"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// MySQL error numbers worth another attempt
+const (
+	mysqlErrTooManyConnections = 1040
+	mysqlErrLockWaitTimeout    = 1205
+	mysqlErrDeadlock           = 1213
+)
+
+// IsRetryable reports whether err is transient: a deadlock, a lock wait
+// timeout, too many connections or a dropped connection
+func IsRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		switch mysqlErr.Number {
+		case mysqlErrTooManyConnections, mysqlErrLockWaitTimeout, mysqlErrDeadlock:
+			return true
+		}
+		return false
+	}
+
+	return errors.Is(err, driver.ErrBadConn) ||
+		errors.Is(err, mysql.ErrInvalidConn) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED)
+}
+
+// WithRetry runs fn up to attempts times while it fails with a retryable
+// error, sleeping backoff before the second attempt and doubling it after.
+// Other errors are returned right away.
+//
+// Retrying is opt-in because fn may run more than once. Reads (the Find*,
+// Count and Exists helpers) and whole transactions through WithTransaction
+// are safe to wrap; a lone insert or a relative update such as
+// "quantity = quantity - 1" is not, since the first attempt may have been
+// applied before the connection dropped.
+func WithRetry(fn func() error, attempts int, backoff time.Duration) error {
+	var err error
+	for attempt := 1; attempt <= max(attempts, 1); attempt++ {
+		err = fn()
+		if err == nil || !IsRetryable(err) || attempt >= attempts {
+			return err
+		}
+
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+
+	return err
+}
@@ -0,0 +1,56 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+func TestWithRetry(t *testing.T) {
+	deadlock := &mysql.MySQLError{Number: mysqlErrDeadlock, Message: "Deadlock found"}
+
+	t.Run("should retry retryable errors until success", func(t *testing.T) {
+		calls := 0
+		err := WithRetry(func() error {
+			calls++
+			if calls < 3 {
+				return fmt.Errorf("failed to query records: %w", deadlock)
+			}
+			return nil
+		}, 3, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if calls != 3 {
+			t.Errorf("expected 3 attempts, got %d", calls)
+		}
+	})
+
+	t.Run("should give up after the last attempt", func(t *testing.T) {
+		calls := 0
+		err := WithRetry(func() error {
+			calls++
+			return deadlock
+		}, 2, 0)
+
+		if !errors.Is(err, deadlock) || calls != 2 {
+			t.Errorf("expected the deadlock after 2 attempts, got %v after %d", err, calls)
+		}
+	})
+
+	t.Run("should not retry other errors", func(t *testing.T) {
+		calls := 0
+		WithRetry(func() error {
+			calls++
+			return sql.ErrNoRows
+		}, 3, 0)
+
+		if calls != 1 {
+			t.Errorf("expected a single attempt, got %d", calls)
+		}
+	})
+}
@@ -0,0 +1,134 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// updateAndReselect runs an UPDATE on a database without RETURNING: the ids
+// of the matching rows are selected and locked first, then the update runs,
+// then the rows are read back by id, all in one transaction. Reading back by
+// id still finds rows the update moved out of the WHERE clause.
+func updateAndReselect[T any](db Querier, tableName, query string, args []interface{}, options *QueryOptions) ([]T, error) {
+	columns, err := returningColumns(options)
+	if err != nil {
+		return nil, err
+	}
+
+	var records []T
+	err = inTransaction(db, func(q Querier) error {
+		ids, err := lockedIDs(q, tableName, options)
+		if err != nil {
+			return err
+		}
+
+		if _, err := runExec(q, query, args...); err != nil {
+			return fmt.Errorf("failed to update records: %w", err)
+		}
+
+		if len(ids) == 0 {
+			records = []T{}
+			return nil
+		}
+
+		condition, idArgs, err := In("id", ids)
+		if err != nil {
+			return err
+		}
+
+		records, err = FindAll[T](q, tableName, &QueryOptions{Select: columns, Where: condition, WhereArgs: idArgs})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return records, nil
+}
+
+// selectAndDelete runs a DELETE on a database without RETURNING: the
+// matching rows are selected and locked first, then deleted, in one
+// transaction
+func selectAndDelete[T any](db Querier, tableName, query string, args []interface{}, options *QueryOptions) ([]T, error) {
+	columns, err := returningColumns(options)
+	if err != nil {
+		return nil, err
+	}
+
+	whereClause, whereArgs, err := buildWhereClause(options)
+	if err != nil {
+		return nil, err
+	}
+
+	selectQuery, err := buildSelectQuery(tableName, &QueryOptions{Select: columns, LockMode: ForUpdate}, whereClause)
+	if err != nil {
+		return nil, err
+	}
+
+	var records []T
+	err = inTransaction(db, func(q Querier) error {
+		rows, err := runQuery(q, selectQuery, whereArgs...)
+		if err != nil {
+			return fmt.Errorf("failed to query records: %w", err)
+		}
+		defer rows.Close()
+
+		records, err = scanRows[T](rows)
+		if err != nil {
+			return err
+		}
+
+		if _, err := runExec(q, query, args...); err != nil {
+			return fmt.Errorf("failed to delete records: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return records, nil
+}
+
+// lockedIDs selects the ids of the rows matching options FOR UPDATE
+func lockedIDs(q Querier, tableName string, options *QueryOptions) ([]interface{}, error) {
+	whereClause, args, err := buildWhereClause(options)
+	if err != nil {
+		return nil, err
+	}
+
+	query, err := buildSelectQuery(tableName, &QueryOptions{Select: "id", LockMode: ForUpdate}, whereClause)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := runQuery(q, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query records: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []interface{}
+	for rows.Next() {
+		var id interface{}
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan id: %w", err)
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
+
+// inTransaction runs fn in a transaction on a *sql.DB. A *sql.Tx, or any
+// other Querier, is used as it is.
+func inTransaction(db Querier, fn func(q Querier) error) error {
+	if conn, ok := db.(*sql.DB); ok {
+		return WithTransaction(conn, func(tx *sql.Tx) error {
+			return fn(tx)
+		})
+	}
+
+	return fn(db)
+}
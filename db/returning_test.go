@@ -0,0 +1,81 @@
+package db
+
+import (
+	"database/sql/driver"
+	"testing"
+)
+
+func TestReturningFallback(t *testing.T) {
+	defer func(dialect Dialect) { CurrentDialect = dialect }(CurrentDialect)
+	CurrentDialect = MySQL
+
+	t.Run("should re-select updated rows by id", func(t *testing.T) {
+		conn, fake := newFakeDB(t, []string{"id"}, []driver.Value{int64(4)})
+
+		records, err := UpdateData[nullableRecord](conn, "products", touchedPayload{Name: "desk"}, &QueryOptions{
+			Where:     "name = ?",
+			WhereArgs: []interface{}{"table"},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(records) != 1 || records[0].ID != 4 {
+			t.Errorf("unexpected records %+v", records)
+		}
+
+		expected := []string{
+			"SELECT id FROM products WHERE name = ? FOR UPDATE",
+			"UPDATE products SET name = ? WHERE name = ?",
+			"SELECT * FROM products WHERE id IN (?)",
+		}
+		if len(fake.Queries) != len(expected) {
+			t.Fatalf("expected %d queries, got %q", len(expected), fake.Queries)
+		}
+		for i := range expected {
+			if fake.Queries[i] != expected[i] {
+				t.Errorf("expected %q, got %q", expected[i], fake.Queries[i])
+			}
+		}
+	})
+
+	t.Run("should select rows before deleting them", func(t *testing.T) {
+		conn, fake := newFakeDB(t, []string{"id"}, []driver.Value{int64(4)})
+
+		records, err := DeleteData[nullableRecord](conn, "products", &QueryOptions{
+			Where:     "id = ?",
+			WhereArgs: []interface{}{4},
+			Returning: []string{"id"},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(records) != 1 || records[0].ID != 4 {
+			t.Errorf("unexpected records %+v", records)
+		}
+
+		expected := []string{
+			"SELECT id FROM products WHERE id = ? FOR UPDATE",
+			"DELETE FROM products WHERE id = ?",
+		}
+		for i := range expected {
+			if fake.Queries[i] != expected[i] {
+				t.Errorf("expected %q, got %q", expected[i], fake.Queries[i])
+			}
+		}
+	})
+
+	t.Run("should skip the re-select when nothing matched", func(t *testing.T) {
+		conn, fake := newFakeDB(t, []string{"id"})
+
+		records, err := UpdateData[nullableRecord](conn, "products", touchedPayload{Name: "desk"}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(records) != 0 || len(fake.Queries) != 2 {
+			t.Errorf("expected no records after 2 queries, got %+v after %q", records, fake.Queries)
+		}
+	})
+}
@@ -0,0 +1,161 @@
+package db
+
+import (
+	"database/sql/driver"
+	"testing"
+	"time"
+)
+
+type nullableRecord struct {
+	ID          int        `db:"id"`
+	Name        string     `db:"name"`
+	Description string     `db:"description"`
+	Price       float64    `db:"price"`
+	Image       *string    `db:"image"`
+	InStock     bool       `db:"inStock"`
+	DeletedAt   *time.Time `db:"deletedAt"`
+	CreatedAt   time.Time  `db:"createdAt"`
+}
+
+func TestScanRows(t *testing.T) {
+	columns := []string{"id", "name", "description", "price", "image", "inStock", "deletedAt", "createdAt"}
+	createdAt := time.Date(2025, 7, 6, 12, 0, 0, 0, time.UTC)
+
+	t.Run("should scan NULL columns into zero values and nil pointers", func(t *testing.T) {
+		conn, _ := newFakeDB(t, columns,
+			[]driver.Value{int64(1), "chair", nil, nil, nil, nil, nil, nil},
+		)
+
+		records, err := FindAll[nullableRecord](conn, "products", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(records) != 1 {
+			t.Fatalf("expected 1 record, got %d", len(records))
+		}
+
+		r := records[0]
+		if r.ID != 1 || r.Name != "chair" {
+			t.Errorf("unexpected non-null values: %+v", r)
+		}
+		if r.Description != "" || r.Price != 0 || r.InStock || !r.CreatedAt.IsZero() {
+			t.Errorf("expected zero values for NULL columns, got %+v", r)
+		}
+		if r.Image != nil || r.DeletedAt != nil {
+			t.Errorf("expected nil pointers for NULL columns, got %+v", r)
+		}
+	})
+
+	t.Run("should parse timestamps returned as text", func(t *testing.T) {
+		conn, _ := newFakeDB(t, columns,
+			[]driver.Value{int64(3), "lamp", nil, nil, nil, nil, []byte("2025-07-06 12:00:00"), "2025-07-06T12:00:00Z"},
+		)
+
+		records, err := FindAll[nullableRecord](conn, "products", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		r := records[0]
+		if !r.CreatedAt.Equal(createdAt) {
+			t.Errorf("expected createdAt %v, got %v", createdAt, r.CreatedAt)
+		}
+		if r.DeletedAt == nil || !r.DeletedAt.Equal(createdAt) {
+			t.Errorf("expected deletedAt %v, got %v", createdAt, r.DeletedAt)
+		}
+	})
+
+	t.Run("should scan present values into plain and pointer fields", func(t *testing.T) {
+		conn, _ := newFakeDB(t, columns,
+			[]driver.Value{int64(2), "desk", "oak", 99.5, "desk.png", true, createdAt, createdAt},
+		)
+
+		records, err := FindAll[nullableRecord](conn, "products", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		r := records[0]
+		if r.Description != "oak" || r.Price != 99.5 || !r.InStock || !r.CreatedAt.Equal(createdAt) {
+			t.Errorf("unexpected values: %+v", r)
+		}
+		if r.Image == nil || *r.Image != "desk.png" {
+			t.Errorf("expected image to be set, got %v", r.Image)
+		}
+		if r.DeletedAt == nil || !r.DeletedAt.Equal(createdAt) {
+			t.Errorf("expected deletedAt to be set, got %v", r.DeletedAt)
+		}
+	})
+}
+
+type baseModel struct {
+	ID        int       `db:"id" insert:"-"`
+	CreatedAt time.Time `db:"createdAt" insert:"-"`
+}
+
+type embeddedRecord struct {
+	baseModel
+	Name string `db:"name"`
+}
+
+func TestEmbeddedStructs(t *testing.T) {
+	t.Run("should scan into embedded struct fields", func(t *testing.T) {
+		createdAt := time.Date(2025, 7, 6, 12, 0, 0, 0, time.UTC)
+		conn, _ := newFakeDB(t, []string{"id", "createdAt", "name"},
+			[]driver.Value{int64(3), createdAt, "lamp"},
+		)
+
+		records, err := FindAll[embeddedRecord](conn, "products", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		r := records[0]
+		if r.ID != 3 || !r.CreatedAt.Equal(createdAt) || r.Name != "lamp" {
+			t.Errorf("unexpected values: %+v", r)
+		}
+	})
+
+	t.Run("should flatten embedded struct fields into insert columns", func(t *testing.T) {
+		columns, _, values := buildInsertData(embeddedRecord{Name: "lamp"})
+
+		if len(columns) != 1 || columns[0] != "name" || values[0] != "lamp" {
+			t.Errorf("expected only the name column, got %v %v", columns, values)
+		}
+	})
+	t.Run("should scan a subset of columns by name in any order", func(t *testing.T) {
+		conn, fake := newFakeDB(t, []string{"name", "id"},
+			[]driver.Value{"stool", int64(4)},
+		)
+
+		records, err := FindAll[nullableRecord](conn, "products", &QueryOptions{Select: "name, id"})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if fake.Queries[0] != "SELECT name, id FROM products" {
+			t.Errorf("unexpected query %q", fake.Queries[0])
+		}
+
+		r := records[0]
+		if r.ID != 4 || r.Name != "stool" || r.Description != "" || r.Image != nil {
+			t.Errorf("expected only id and name to be set, got %+v", r)
+		}
+	})
+
+	t.Run("should skip columns without a matching field", func(t *testing.T) {
+		conn, _ := newFakeDB(t, []string{"id", "total", "name"},
+			[]driver.Value{int64(5), int64(3), "bench"},
+		)
+
+		records, err := FindAll[nullableRecord](conn, "products", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if records[0].ID != 5 || records[0].Name != "bench" {
+			t.Errorf("unexpected record %+v", records[0])
+		}
+	})
+}
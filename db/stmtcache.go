@@ -0,0 +1,176 @@
+package db
+
+import (
+	"container/list"
+	"database/sql"
+	"errors"
+	"sync"
+)
+
+// StmtCache reuses prepared statements keyed by their SQL.
+//
+// Without it, every query with args on the MySQL driver costs three round
+// trips (prepare, execute, close). A cached statement is prepared once and
+// each later call is a single execute, which is what dominates tight loops
+// such as repeated InsertOne calls or GetUserByEmail on every login.
+//
+// Every statement stays prepared on the server, once per connection, so the
+// cache holds at most capacity of them and closes the least recently used
+// one to make room. Queries whose text varies, such as IN lists of different
+// lengths, would otherwise run into MySQL's max_prepared_stmt_count.
+type StmtCache struct {
+	db       *sql.DB
+	capacity int
+	mu       sync.Mutex
+	order    *list.List // front is the most recently used
+	stmts    map[string]*list.Element
+}
+
+// cachedStatement is a statement of the cache. An evicted statement still in
+// use is closed by its last user.
+type cachedStatement struct {
+	query   string
+	stmt    *sql.Stmt
+	users   int
+	evicted bool
+}
+
+var stmtCaches sync.Map // *sql.DB -> *StmtCache
+
+// EnableStatementCache makes every query the package runs against db go
+// through a statement cache of at most capacity statements. A capacity below
+// 1 disables the cache: queries run unprepared as before. Close the returned
+// cache on shutdown.
+func EnableStatementCache(db *sql.DB, capacity int) *StmtCache {
+	cache := &StmtCache{db: db, capacity: capacity, order: list.New(), stmts: make(map[string]*list.Element)}
+	if capacity < 1 {
+		return cache
+	}
+
+	actual, _ := stmtCaches.LoadOrStore(db, cache)
+	return actual.(*StmtCache)
+}
+
+// Prepare returns the cached statement for query, preparing it on first use.
+// Call release once done with the statement, it may be closed afterwards.
+func (c *StmtCache) Prepare(query string) (stmt *sql.Stmt, release func(), err error) {
+	if c.capacity < 1 {
+		stmt, err := c.db.Prepare(query)
+		if err != nil {
+			return nil, nil, err
+		}
+		return stmt, func() { stmt.Close() }, nil
+	}
+
+	if stmt, release, ok := c.use(query); ok {
+		return stmt, release, nil
+	}
+
+	// Preparing is a round trip to the server, so it must not hold up the
+	// queries whose statements are already cached
+	stmt, err = c.db.Prepare(query)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Another caller may have prepared the same query in the meantime, keep
+	// theirs
+	element, ok := c.stmts[query]
+	if ok {
+		stmt.Close()
+		c.order.MoveToFront(element)
+	} else {
+		element = c.order.PushFront(&cachedStatement{query: query, stmt: stmt})
+		c.stmts[query] = element
+
+		for c.order.Len() > c.capacity {
+			c.evict(c.order.Back())
+		}
+	}
+
+	cached := element.Value.(*cachedStatement)
+	cached.users++
+
+	return cached.stmt, func() { c.release(cached) }, nil
+}
+
+// use returns the cached statement for query, if there is one
+func (c *StmtCache) use(query string) (*sql.Stmt, func(), bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.stmts[query]
+	if !ok {
+		return nil, nil, false
+	}
+
+	c.order.MoveToFront(element)
+	cached := element.Value.(*cachedStatement)
+	cached.users++
+
+	return cached.stmt, func() { c.release(cached) }, true
+}
+
+func (c *StmtCache) release(cached *cachedStatement) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	cached.users--
+	if cached.evicted && cached.users == 0 {
+		cached.stmt.Close()
+	}
+}
+
+// evict drops a statement from the cache, closing it unless it is in use
+func (c *StmtCache) evict(element *list.Element) error {
+	cached := element.Value.(*cachedStatement)
+	c.order.Remove(element)
+	delete(c.stmts, cached.query)
+
+	cached.evicted = true
+	if cached.users > 0 {
+		return nil
+	}
+
+	return cached.stmt.Close()
+}
+
+// Close closes every cached statement and detaches the cache from its database
+func (c *StmtCache) Close() error {
+	stmtCaches.CompareAndDelete(c.db, c)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var errs []error
+	for _, element := range c.stmts {
+		errs = append(errs, c.evict(element))
+	}
+
+	return errors.Join(errs...)
+}
+
+// cachedStmt returns a prepared statement for query and its release func when
+// q has a statement cache
+func cachedStmt(q Querier, query string) (*sql.Stmt, func()) {
+	db, ok := q.(*sql.DB)
+	if !ok {
+		return nil, nil
+	}
+
+	cache, ok := stmtCaches.Load(db)
+	if !ok {
+		return nil, nil
+	}
+
+	stmt, release, err := cache.(*StmtCache).Prepare(query)
+	if err != nil {
+		// Fall back to an unprepared query, which will surface the error
+		return nil, nil
+	}
+
+	return stmt, release
+}
@@ -0,0 +1,212 @@
+package db
+
+import (
+	"database/sql"
+	"os"
+	"sync"
+	"testing"
+
+	_ "github.com/go-sql-driver/mysql"
+)
+
+type benchRecord struct {
+	ID   int    `db:"id" insert:"-"`
+	Name string `db:"name"`
+}
+
+func TestStmtCache(t *testing.T) {
+	t.Run("should prepare each query once and reuse it", func(t *testing.T) {
+		conn, fake := newFakeDB(t, []string{"id", "name"})
+
+		cache := EnableStatementCache(conn, 10)
+		defer cache.Close()
+
+		for i := 0; i < 3; i++ {
+			if _, err := FindAll[benchRecord](conn, "bench", nil); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		if len(cache.stmts) != 1 {
+			t.Errorf("expected 1 cached statement, got %d", len(cache.stmts))
+		}
+		if len(fake.Queries) != 3 {
+			t.Errorf("expected 3 executions, got %d", len(fake.Queries))
+		}
+	})
+
+	t.Run("should detach the cache on close", func(t *testing.T) {
+		conn, _ := newFakeDB(t, []string{"id", "name"})
+
+		cache := EnableStatementCache(conn, 10)
+		if err := cache.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		if stmt, _ := cachedStmt(conn, "SELECT 1"); stmt != nil {
+			t.Error("expected no cached statement after close")
+		}
+	})
+
+	t.Run("should close the least recently used statement when full", func(t *testing.T) {
+		conn, _ := newFakeDB(t, []string{"id", "name"})
+
+		cache := EnableStatementCache(conn, 2)
+		defer cache.Close()
+
+		prepare := func(query string) (*sql.Stmt, func()) {
+			stmt, release, err := cache.Prepare(query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return stmt, release
+		}
+
+		first, release := prepare("SELECT 1")
+		release()
+		_, release = prepare("SELECT 2")
+		release()
+		_, release = prepare("SELECT 1")
+		release()
+		_, release = prepare("SELECT 3")
+		release()
+
+		if len(cache.stmts) != 2 {
+			t.Fatalf("expected 2 cached statements, got %d", len(cache.stmts))
+		}
+		if _, ok := cache.stmts["SELECT 2"]; ok {
+			t.Error("expected SELECT 2 to be evicted")
+		}
+		if _, err := first.Exec(); err != nil {
+			t.Errorf("expected SELECT 1 to stay open, got %v", err)
+		}
+	})
+
+	t.Run("should keep an evicted statement open while it is in use", func(t *testing.T) {
+		conn, _ := newFakeDB(t, []string{"id", "name"})
+
+		cache := EnableStatementCache(conn, 1)
+		defer cache.Close()
+
+		stmt, release, err := cache.Prepare("SELECT 1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, releaseOther, err := cache.Prepare("SELECT 2")
+		if err != nil {
+			t.Fatal(err)
+		}
+		releaseOther()
+
+		if _, err := stmt.Exec(); err != nil {
+			t.Errorf("expected the statement to stay usable, got %v", err)
+		}
+
+		release()
+		if _, err := stmt.Exec(); err == nil {
+			t.Error("expected the statement to be closed after its release")
+		}
+	})
+
+	t.Run("should treat a capacity below 1 as disabled", func(t *testing.T) {
+		conn, _ := newFakeDB(t, []string{"id", "name"})
+
+		cache := EnableStatementCache(conn, 0)
+		defer cache.Close()
+
+		if stmt, _ := cachedStmt(conn, "SELECT 1"); stmt != nil {
+			t.Error("expected queries to bypass a disabled cache")
+		}
+
+		stmt, release, err := cache.Prepare("SELECT 1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := stmt.Exec(); err != nil {
+			t.Errorf("expected the statement to be usable until its release, got %v", err)
+		}
+
+		release()
+		if _, err := stmt.Exec(); err == nil {
+			t.Error("expected the statement to be closed after its release")
+		}
+	})
+
+	t.Run("should share one statement between concurrent first uses", func(t *testing.T) {
+		conn, _ := newFakeDB(t, []string{"id", "name"})
+
+		cache := EnableStatementCache(conn, 10)
+		defer cache.Close()
+
+		var wg sync.WaitGroup
+		stmts := make([]*sql.Stmt, 8)
+		for i := range stmts {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+
+				stmt, release, err := cache.Prepare("SELECT 1")
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				defer release()
+
+				if _, err := stmt.Exec(); err != nil {
+					t.Errorf("expected an open statement, got %v", err)
+				}
+				stmts[i] = stmt
+			}()
+		}
+		wg.Wait()
+
+		if len(cache.stmts) != 1 {
+			t.Fatalf("expected 1 cached statement, got %d", len(cache.stmts))
+		}
+		cached := cache.stmts["SELECT 1"].Value.(*cachedStatement)
+		for _, stmt := range stmts {
+			if stmt != cached.stmt {
+				t.Fatal("expected every caller to end up with the cached statement")
+			}
+		}
+	})
+}
+
+// BenchmarkInsertOne compares InsertOne with and without the statement cache
+// against the MySQL database in TEST_MYSQL_DSN, e.g.
+//
+//	TEST_MYSQL_DSN="root:@tcp(127.0.0.1:3306)/ecom" go test -bench InsertOne ./db
+func BenchmarkInsertOne(b *testing.B) {
+	dsn := os.Getenv("TEST_MYSQL_DSN")
+	if dsn == "" {
+		b.Skip("TEST_MYSQL_DSN is not set")
+	}
+
+	conn, err := sql.Open("mysql", dsn)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer conn.Close()
+
+	// Temporary tables are per connection
+	conn.SetMaxOpenConns(1)
+
+	if _, err := conn.Exec("CREATE TEMPORARY TABLE bench (id INT AUTO_INCREMENT PRIMARY KEY, name VARCHAR(255))"); err != nil {
+		b.Fatal(err)
+	}
+
+	run := func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, err := InsertOne[benchRecord](conn, "bench", benchRecord{Name: "bench"}); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+
+	b.Run("uncached", run)
+	b.Run("cached", func(b *testing.B) {
+		cache := EnableStatementCache(conn, 10)
+		defer cache.Close()
+		run(b)
+	})
+}
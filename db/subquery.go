@@ -0,0 +1,62 @@
+package db
+
+// Subquery is a built query together with its args. Passed as a WHERE arg it
+// replaces its "?" placeholder, e.g. Where("id NOT IN ?", sub), and its args are
+// spliced into the outer query's args at that position.
+type Subquery struct {
+	Query string
+	Args  []interface{}
+}
+
+// expandSubqueries inlines every *Subquery arg into the placeholder it is
+// bound to and returns the condition with the flattened args
+func expandSubqueries(condition string, args []interface{}) (string, []interface{}) {
+	hasSubquery := false
+	for _, arg := range args {
+		if _, ok := arg.(*Subquery); ok {
+			hasSubquery = true
+			break
+		}
+	}
+
+	if !hasSubquery {
+		return condition, args
+	}
+
+	var expanded []byte
+	var expandedArgs []interface{}
+	argIndex := 0
+	inString := false
+
+	for i := 0; i < len(condition); i++ {
+		c := condition[i]
+
+		if c == '\'' {
+			inString = !inString
+		}
+
+		if c != '?' || inString || argIndex >= len(args) {
+			expanded = append(expanded, c)
+			continue
+		}
+
+		arg := args[argIndex]
+		argIndex++
+
+		if sub, ok := arg.(*Subquery); ok {
+			expanded = append(expanded, '(')
+			expanded = append(expanded, sub.Query...)
+			expanded = append(expanded, ')')
+			expandedArgs = append(expandedArgs, sub.Args...)
+			continue
+		}
+
+		expanded = append(expanded, c)
+		expandedArgs = append(expandedArgs, arg)
+	}
+
+	// Args without a placeholder are passed through unchanged
+	expandedArgs = append(expandedArgs, args[argIndex:]...)
+
+	return string(expanded), expandedArgs
+}
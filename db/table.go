@@ -0,0 +1,133 @@
+package db
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"unicode"
+)
+
+// Tabler lets a type name its own table when the derived name doesn't fit
+type Tabler interface {
+	TableName() string
+}
+
+// TableName resolves the table of T: TableName() when T (or *T) implements
+// Tabler, otherwise the snake_case plural of the type name, so Product maps
+// to "products" and RefreshToken to "refresh_tokens".
+func TableName[T any]() string {
+	var zero T
+	if t, ok := any(zero).(Tabler); ok {
+		return t.TableName()
+	}
+	if t, ok := any(&zero).(Tabler); ok {
+		return t.TableName()
+	}
+
+	return pluralize(snakeCase(reflect.TypeOf(zero).Name()))
+}
+
+// snakeCase converts a Go type name to snake_case, keeping acronyms
+// together ("HTTPRequest" becomes "http_request")
+func snakeCase(name string) string {
+	runes := []rune(name)
+	var b strings.Builder
+
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			startsWord := i > 0 && (unicode.IsLower(runes[i-1]) ||
+				(i+1 < len(runes) && unicode.IsLower(runes[i+1])))
+			if startsWord {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+
+	return b.String()
+}
+
+// pluralize applies the regular English plural rules
+func pluralize(word string) string {
+	switch {
+	case word == "":
+		return word
+	case strings.HasSuffix(word, "y") && len(word) > 1 && !strings.ContainsAny(word[len(word)-2:len(word)-1], "aeiou"):
+		return word[:len(word)-1] + "ies"
+	case strings.HasSuffix(word, "s"), strings.HasSuffix(word, "x"), strings.HasSuffix(word, "z"),
+		strings.HasSuffix(word, "ch"), strings.HasSuffix(word, "sh"):
+		return word + "es"
+	default:
+		return word + "s"
+	}
+}
+
+// Columns lists the columns named by the db tags of T, embedded structs
+// included, in field order. Set QueryOptions.Select to them to fetch only
+// what T can hold instead of SELECT *.
+func Columns[T any]() []string {
+	var zero T
+	v := reflect.ValueOf(&zero).Elem()
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var columns []string
+	for _, sf := range structFields(v) {
+		if !sf.field.IsExported() {
+			continue
+		}
+
+		name := strings.Split(sf.field.Tag.Get("db"), ",")[0]
+		if name == "" || name == "-" {
+			continue
+		}
+
+		columns = append(columns, name)
+	}
+
+	return columns
+}
+
+// withColumns returns options selecting the Columns of T, unless options
+// pick their own or T has no tagged fields. The caller's options are not
+// modified.
+func withColumns[T any](options *QueryOptions) *QueryOptions {
+	columns := Columns[T]()
+	if len(columns) == 0 || (options != nil && options.Select != "") {
+		return options
+	}
+
+	selected := QueryOptions{}
+	if options != nil {
+		selected = *options
+	}
+	selected.Select = strings.Join(columns, ", ")
+
+	return &selected
+}
+
+// FindAllT is FindAll with the table resolved from T, selecting the Columns
+// of T
+func FindAllT[T any](db Querier, options *QueryOptions) ([]T, error) {
+	return FindAllContext[T](context.Background(), db, TableName[T](), withColumns[T](options))
+}
+
+// FindOneT is FindOne with the table resolved from T, selecting the Columns
+// of T
+func FindOneT[T any](db Querier, options *QueryOptions) (*T, error) {
+	return FindOneContext[T](context.Background(), db, TableName[T](), withColumns[T](options))
+}
+
+// FindByPKT is FindByPK with the table resolved from T, selecting the
+// Columns of T
+func FindByPKT[T any](db Querier, pk interface{}) (*T, error) {
+	return FindOneT[T](db, &QueryOptions{Where: "id = ?", WhereArgs: []interface{}{pk}})
+}
+
+// FindByIDsT is FindByIDs with the table resolved from T, selecting the
+// Columns of T
+func FindByIDsT[T any](db Querier, ids []interface{}) ([]T, error) {
+	return findByIDs[T](context.Background(), db, TableName[T](), ids, strings.Join(Columns[T](), ", "))
+}
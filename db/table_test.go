@@ -0,0 +1,109 @@
+package db
+
+import (
+	"database/sql/driver"
+	"strings"
+	"testing"
+)
+
+type Product struct{}
+type RefreshToken struct{}
+type Category struct{}
+type Address struct{}
+type HTTPRequest struct{}
+
+type customTable struct{}
+
+func (customTable) TableName() string { return "email_verification_tokens" }
+
+type pointerTable struct{}
+
+func (*pointerTable) TableName() string { return "legacy_items" }
+
+func TestTableName(t *testing.T) {
+	tests := []struct {
+		name     string
+		actual   string
+		expected string
+	}{
+		{"single word", TableName[Product](), "products"},
+		{"camel case", TableName[RefreshToken](), "refresh_tokens"},
+		{"consonant y", TableName[Category](), "categories"},
+		{"sibilant", TableName[Address](), "addresses"},
+		{"acronym", TableName[HTTPRequest](), "http_requests"},
+		{"value receiver", TableName[customTable](), "email_verification_tokens"},
+		{"pointer receiver", TableName[pointerTable](), "legacy_items"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.actual != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, tt.actual)
+			}
+		})
+	}
+
+	t.Run("should query the resolved table", func(t *testing.T) {
+		conn, fake := newFakeDB(t, []string{"id"}, []driver.Value{int64(1)})
+
+		if _, err := FindAllT[Product](conn, nil); err != nil {
+			t.Fatal(err)
+		}
+
+		if fake.Queries[0] != "SELECT * FROM products" {
+			t.Errorf("unexpected query %q", fake.Queries[0])
+		}
+	})
+}
+
+type columnsBase struct {
+	ID int `db:"id"`
+}
+
+type columnsRecord struct {
+	columnsBase
+	Name     string `db:"name"`
+	Price    float64
+	Internal string `db:"-"`
+	hidden   string `db:"hidden"`
+}
+
+func (columnsRecord) TableName() string { return "products" }
+
+func TestColumns(t *testing.T) {
+	t.Run("should list the tagged columns in field order", func(t *testing.T) {
+		columns := Columns[columnsRecord]()
+
+		if strings.Join(columns, ", ") != "id, name" {
+			t.Errorf("expected id and name, got %v", columns)
+		}
+	})
+
+	t.Run("should select the columns in the T finders", func(t *testing.T) {
+		conn, fake := newFakeDB(t, []string{"id", "name"}, []driver.Value{int64(1), "chair"})
+
+		options := &QueryOptions{Where: "id = ?", WhereArgs: []interface{}{1}}
+		if _, err := FindAllT[columnsRecord](conn, options); err != nil {
+			t.Fatal(err)
+		}
+
+		if fake.Queries[0] != "SELECT id, name FROM products WHERE id = ?" {
+			t.Errorf("unexpected query %q", fake.Queries[0])
+		}
+		if options.Select != "" {
+			t.Errorf("expected the caller's options to be left alone, got %q", options.Select)
+		}
+	})
+
+	t.Run("should keep an explicit select", func(t *testing.T) {
+		conn, fake := newFakeDB(t, []string{"name"}, []driver.Value{"chair"})
+
+		if _, err := FindAllT[columnsRecord](conn, &QueryOptions{Select: "name"}); err != nil {
+			t.Fatal(err)
+		}
+
+		if fake.Queries[0] != "SELECT name FROM products" {
+			t.Errorf("unexpected query %q", fake.Queries[0])
+		}
+	})
+}
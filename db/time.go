@@ -0,0 +1,51 @@
+package db
+
+import (
+	"fmt"
+	"time"
+)
+
+// TimeLayouts are tried in order when a driver returns a timestamp as text
+// (e.g. SQLite, or MySQL without parseTime=true)
+var TimeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05.999999999-07:00",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+}
+
+// nullTime scans native timestamps as well as textual ones, which
+// sql.NullTime rejects
+type nullTime struct {
+	Time  time.Time
+	Valid bool
+}
+
+func (n *nullTime) Scan(src interface{}) error {
+	switch value := src.(type) {
+	case nil:
+		n.Time, n.Valid = time.Time{}, false
+		return nil
+	case time.Time:
+		n.Time, n.Valid = value, true
+		return nil
+	case []byte:
+		return n.parse(string(value))
+	case string:
+		return n.parse(value)
+	default:
+		return fmt.Errorf("cannot scan %T into a time", src)
+	}
+}
+
+func (n *nullTime) parse(value string) error {
+	for _, layout := range TimeLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			n.Time, n.Valid = t, true
+			return nil
+		}
+	}
+
+	return fmt.Errorf("cannot parse %q as a time", value)
+}
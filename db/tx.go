@@ -0,0 +1,34 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// WithTransaction runs fn inside a transaction, committing when it returns nil
+// and rolling back when it returns an error or panics. Pass tx to the
+// package's helpers to run them as part of the transaction.
+func WithTransaction(db *sql.DB, fn func(tx *sql.Tx) error) (err error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			panic(p)
+		}
+	}()
+
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
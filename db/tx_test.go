@@ -0,0 +1,68 @@
+package db
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"testing"
+)
+
+func TestWithTransaction(t *testing.T) {
+	t.Run("should run helpers against the transaction", func(t *testing.T) {
+		conn, fake := newFakeDB(t, []string{"id", "name"}, []driver.Value{int64(1), "chair"})
+
+		var records []nullableRecord
+		err := WithTransaction(conn, func(tx *sql.Tx) error {
+			var err error
+			records, err = FindAll[nullableRecord](tx, "products", &QueryOptions{LockMode: ForUpdate})
+			return err
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(records) != 1 || records[0].Name != "chair" {
+			t.Errorf("unexpected records %+v", records)
+		}
+
+		if fake.Queries[0] != "SELECT * FROM products FOR UPDATE" {
+			t.Errorf("unexpected query %q", fake.Queries[0])
+		}
+	})
+
+	t.Run("should return the error of fn", func(t *testing.T) {
+		conn, _ := newFakeDB(t, nil)
+		expected := errors.New("out of stock")
+
+		err := WithTransaction(conn, func(tx *sql.Tx) error {
+			return expected
+		})
+		if !errors.Is(err, expected) {
+			t.Errorf("expected %v, got %v", expected, err)
+		}
+	})
+	t.Run("should run the bulk helpers as part of the transaction", func(t *testing.T) {
+		conn, fake := newFakeDB(t, nil)
+		expected := errors.New("out of stock")
+
+		err := WithTransaction(conn, func(tx *sql.Tx) error {
+			if _, err := BulkInsert[benchRecord](tx, "products", []interface{}{benchRecord{Name: "chair"}, benchRecord{Name: "desk"}}); err != nil {
+				return err
+			}
+			if _, err := BulkUpdateByIDs[touchedRecord](tx, "products", touchedPayload{Name: "lamp"}, []interface{}{1, 2}); err != nil {
+				return err
+			}
+			return expected
+		})
+		if !errors.Is(err, expected) {
+			t.Fatalf("expected %v, got %v", expected, err)
+		}
+
+		if len(fake.Queries) != 3 {
+			t.Errorf("expected 2 inserts and an update, got %v", fake.Queries)
+		}
+		if fake.Rollbacks != 1 || fake.Commits != 0 {
+			t.Errorf("expected the caller's rollback to undo the writes, got %d rollbacks and %d commits", fake.Rollbacks, fake.Commits)
+		}
+	})
+}
@@ -0,0 +1,96 @@
+package db
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Union runs (a) UNION [ALL] (b) and scans the combined rows into T. Both
+// builders must select the same columns.
+func Union[T any](db Querier, a, b *JoinBuilder, all bool) ([]T, error) {
+	columnsA := selectColumns(a.GetOptions().Select)
+	columnsB := selectColumns(b.GetOptions().Select)
+	if strings.Join(columnsA, ",") != strings.Join(columnsB, ",") {
+		return nil, fmt.Errorf("union queries select different columns: %v and %v", columnsA, columnsB)
+	}
+
+	queryA, argsA, err := buildJoinQuery(a.GetTableName(), a.GetOptions())
+	if err != nil {
+		return nil, err
+	}
+
+	queryB, argsB, err := buildJoinQuery(b.GetTableName(), b.GetOptions())
+	if err != nil {
+		return nil, err
+	}
+
+	operator := "UNION"
+	if all {
+		operator = "UNION ALL"
+	}
+
+	query := fmt.Sprintf("(%s) %s (%s)", queryA, operator, queryB)
+	args := append(append([]interface{}{}, argsA...), argsB...)
+
+	rows, err := runQuery(db, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query union: %w", err)
+	}
+	defer rows.Close()
+
+	return scanRows[T](rows)
+}
+
+// selectColumns returns the output column names of a SELECT list, using the
+// alias when one is given ("u.id AS userId" -> "userId", "p.name" -> "name")
+func selectColumns(selectClause string) []string {
+	if strings.TrimSpace(selectClause) == "" {
+		return []string{"*"}
+	}
+
+	var columns []string
+	depth := 0
+	start := 0
+
+	for i := 0; i <= len(selectClause); i++ {
+		if i < len(selectClause) {
+			switch selectClause[i] {
+			case '(':
+				depth++
+				continue
+			case ')':
+				depth--
+				continue
+			case ',':
+				if depth > 0 {
+					continue
+				}
+			default:
+				continue
+			}
+		}
+
+		columns = append(columns, columnName(selectClause[start:i]))
+		start = i + 1
+	}
+
+	return columns
+}
+
+func columnName(expr string) string {
+	fields := strings.Fields(expr)
+	if len(fields) == 0 {
+		return ""
+	}
+
+	if len(fields) >= 3 && strings.EqualFold(fields[len(fields)-2], "AS") {
+		return fields[len(fields)-1]
+	}
+
+	name := fields[len(fields)-1]
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		name = name[i+1:]
+	}
+
+	return name
+}
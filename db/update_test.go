@@ -0,0 +1,210 @@
+package db
+
+import (
+	"database/sql/driver"
+	"strings"
+	"testing"
+	"time"
+)
+
+type touchedRecord struct {
+	ID        int       `db:"id" insert:"-"`
+	Name      string    `db:"name"`
+	UpdatedAt time.Time `db:"updatedAt"`
+}
+
+type touchedPayload struct {
+	Name string `db:"name"`
+}
+
+func TestUpdateDataTouchesUpdatedAt(t *testing.T) {
+	defer func(dialect Dialect) { CurrentDialect = dialect }(CurrentDialect)
+	CurrentDialect = PostgreSQL
+
+	columns := []string{"id", "name", "updatedAt"}
+
+	t.Run("should set updatedAt to the current time", func(t *testing.T) {
+		conn, fake := newFakeDB(t, columns)
+
+		before := time.Now()
+		_, err := UpdateData[touchedRecord](conn, "products", touchedPayload{Name: "desk"}, &QueryOptions{
+			Where:     "id = ?",
+			WhereArgs: []interface{}{1},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		query := fake.Queries[0]
+		if !strings.Contains(query, "SET name = ?, updatedAt = ? WHERE id = ?") {
+			t.Fatalf("expected updatedAt in the SET clause, got %q", query)
+		}
+
+		updatedAt, ok := fake.Args[0][1].(time.Time)
+		if !ok {
+			t.Fatalf("expected a time argument, got %T", fake.Args[0][1])
+		}
+		if updatedAt.Before(before) {
+			t.Errorf("expected updatedAt to be at least %v, got %v", before, updatedAt)
+		}
+	})
+
+	t.Run("should leave updatedAt alone when managed manually", func(t *testing.T) {
+		conn, fake := newFakeDB(t, columns)
+
+		_, err := UpdateData[touchedRecord](conn, "products", touchedPayload{Name: "desk"}, &QueryOptions{
+			Where:           "id = ?",
+			WhereArgs:       []interface{}{1},
+			ManualUpdatedAt: true,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if strings.Contains(fake.Queries[0], "updatedAt") {
+			t.Errorf("expected no updatedAt in %q", fake.Queries[0])
+		}
+	})
+}
+
+func TestBulkUpdateByIDs(t *testing.T) {
+	columns := []string{"id", "name", "updatedAt"}
+
+	t.Run("should update every id in a single statement", func(t *testing.T) {
+		conn, fake := newFakeDB(t, columns, nil, nil, nil)
+
+		affected, err := BulkUpdateByIDs[touchedRecord](conn, "products", touchedPayload{Name: "desk"}, []interface{}{1, 2, 3})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if affected != 3 {
+			t.Errorf("expected 3 affected rows, got %d", affected)
+		}
+
+		if len(fake.Queries) != 1 {
+			t.Fatalf("expected a single statement, got %v", fake.Queries)
+		}
+
+		if !strings.HasSuffix(fake.Queries[0], "WHERE id IN (?, ?, ?)") {
+			t.Errorf("expected an IN condition, got %q", fake.Queries[0])
+		}
+
+		if len(fake.Args[0]) != 5 {
+			t.Errorf("expected name, updatedAt and 3 ids as args, got %v", fake.Args[0])
+		}
+	})
+
+	t.Run("should do nothing without ids", func(t *testing.T) {
+		conn, fake := newFakeDB(t, columns)
+
+		affected, err := BulkUpdateByIDs[touchedRecord](conn, "products", touchedPayload{Name: "desk"}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if affected != 0 || len(fake.Queries) != 0 {
+			t.Errorf("expected no statement, got %v", fake.Queries)
+		}
+	})
+}
+
+func TestReturning(t *testing.T) {
+	defer func(dialect Dialect) { CurrentDialect = dialect }(CurrentDialect)
+	CurrentDialect = PostgreSQL
+
+	type idOnly struct {
+		ID int `db:"id"`
+	}
+
+	t.Run("should return only the requested columns", func(t *testing.T) {
+		conn, fake := newFakeDB(t, []string{"id"}, []driver.Value{int64(4)})
+
+		records, err := DeleteData[idOnly](conn, "products", &QueryOptions{
+			Where:     "id = ?",
+			WhereArgs: []interface{}{4},
+			Returning: []string{"id"},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(records) != 1 || records[0].ID != 4 {
+			t.Errorf("unexpected records %+v", records)
+		}
+
+		if !strings.HasSuffix(fake.Queries[0], " RETURNING id") {
+			t.Errorf("expected RETURNING id, got %q", fake.Queries[0])
+		}
+	})
+
+	t.Run("should default to every column", func(t *testing.T) {
+		conn, fake := newFakeDB(t, []string{"id"})
+
+		if _, err := DeleteData[idOnly](conn, "products", nil); err != nil {
+			t.Fatal(err)
+		}
+
+		if !strings.HasSuffix(fake.Queries[0], " RETURNING *") {
+			t.Errorf("expected RETURNING *, got %q", fake.Queries[0])
+		}
+	})
+
+	t.Run("should reject an invalid column", func(t *testing.T) {
+		conn, _ := newFakeDB(t, []string{"id"})
+
+		_, err := DeleteData[idOnly](conn, "products", &QueryOptions{Returning: []string{"id; DROP TABLE users"}})
+		if err == nil {
+			t.Error("expected an error")
+		}
+	})
+}
+
+type patchPayload struct {
+	Name        *string `db:"name"`
+	Description *string `db:"description"`
+}
+
+func TestUpdatePatch(t *testing.T) {
+	columns := []string{"id", "name", "updatedAt"}
+
+	t.Run("should set a pointer to the zero value", func(t *testing.T) {
+		conn, fake := newFakeDB(t, columns)
+
+		empty := ""
+		_, err := UpdatePatch[touchedRecord](conn, "products", patchPayload{Description: &empty}, &QueryOptions{
+			Where:           "id = ?",
+			WhereArgs:       []interface{}{1},
+			ManualUpdatedAt: true,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected := "UPDATE products SET description = ? WHERE id = ?"
+		if fake.Queries[0] != expected {
+			t.Errorf("expected %q, got %q", expected, fake.Queries[0])
+		}
+		if fake.Args[0][0] != "" {
+			t.Errorf("expected an empty description, got %v", fake.Args[0][0])
+		}
+	})
+
+	t.Run("should fail when every pointer is nil", func(t *testing.T) {
+		conn, _ := newFakeDB(t, columns)
+
+		_, err := UpdatePatch[touchedRecord](conn, "products", patchPayload{}, nil)
+		if err != ErrNoFieldsToUpdate {
+			t.Errorf("expected ErrNoFieldsToUpdate, got %v", err)
+		}
+	})
+
+	t.Run("should reject fields that are not pointers", func(t *testing.T) {
+		conn, _ := newFakeDB(t, columns)
+
+		_, err := UpdatePatch[touchedRecord](conn, "products", touchedPayload{Name: "desk"}, nil)
+		if err == nil {
+			t.Error("expected an error for a non-pointer field")
+		}
+	})
+}
@@ -0,0 +1,59 @@
+package db
+
+import (
+	"database/sql/driver"
+	"testing"
+)
+
+func TestUpsertOne(t *testing.T) {
+	columns := []string{"id", "name"}
+
+	t.Run("should update the given columns on a duplicate key", func(t *testing.T) {
+		conn, fake := newFakeDB(t, columns)
+
+		_, err := UpsertOne[touchedRecord](conn, "products", touchedPayload{Name: "desk"}, []string{"name"})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected := "INSERT INTO products (name) VALUES (?) ON DUPLICATE KEY UPDATE name = VALUES(name)"
+		if fake.Queries[0] != expected {
+			t.Errorf("expected %q, got %q", expected, fake.Queries[0])
+		}
+	})
+
+	t.Run("should tell an insert from an update", func(t *testing.T) {
+		for _, tt := range []struct {
+			affected int
+			inserted bool
+		}{
+			{0, false},
+			{1, true},
+			{2, false},
+		} {
+			rows := make([][]driver.Value, tt.affected)
+			for i := range rows {
+				rows[i] = []driver.Value{int64(i + 1), "desk"}
+			}
+			conn, _ := newFakeDB(t, columns, rows...)
+
+			inserted, err := UpsertOne[touchedRecord](conn, "products", touchedPayload{Name: "desk"}, []string{"name"})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if inserted != tt.inserted {
+				t.Errorf("%d affected rows: expected inserted %v, got %v", tt.affected, tt.inserted, inserted)
+			}
+		}
+	})
+
+	t.Run("should require columns to update", func(t *testing.T) {
+		conn, _ := newFakeDB(t, columns)
+
+		_, err := UpsertOne[touchedRecord](conn, "products", touchedPayload{Name: "desk"}, nil)
+		if err != ErrNoFieldsToUpdate {
+			t.Errorf("expected ErrNoFieldsToUpdate, got %v", err)
+		}
+	})
+}
@@ -1,10 +1,14 @@
 package db
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"reflect"
+	"sort"
 	"strings"
+	"time"
 )
 
 type CountResult[T any] struct {
@@ -12,27 +16,81 @@ type CountResult[T any] struct {
 	Count int `json:"count"`
 }
 
+// ErrNoFieldsToUpdate is returned when an update payload has no columns to set
+var ErrNoFieldsToUpdate = errors.New("no fields to update")
+
+// UpdatedAtColumn is set to the current time by UpdateData when the target type has it
+const UpdatedAtColumn = "updatedAt"
+
+// DefaultSoftDeleteColumn is used by SoftDelete when QueryOptions.SoftDeleteColumn is empty
+const DefaultSoftDeleteColumn = "deleted_at"
+
 type QueryOptions struct {
 	Where     string        `json:"where,omitempty"`
 	WhereArgs []interface{} `json:"whereArgs,omitempty"`
 	OrderBy   string        `json:"orderBy,omitempty"`
 	Limit     int           `json:"limit,omitempty"`
 	Offset    int           `json:"offset,omitempty"`
+	Distinct  bool          `json:"distinct,omitempty"`
+	Select    string        `json:"select,omitempty"` // Columns to fetch, defaults to *
+
+	// Conditions are ANDed together with Where, so filters can be composed
+	// without building the SQL string by hand
+	Conditions []WhereClause `json:"conditions,omitempty"`
+
+	// Order is the preferred, validated alternative to OrderBy and takes
+	// precedence over it when set
+	Order []OrderByClause `json:"order,omitempty"`
+
+	// SoftDeleteColumn marks the table as soft-deletable; rows where the column
+	// is set are filtered out unless IncludeDeleted is true
+	SoftDeleteColumn string `json:"softDeleteColumn,omitempty"`
+	IncludeDeleted   bool   `json:"includeDeleted,omitempty"`
+
+	// ManualUpdatedAt stops UpdateData from setting UpdatedAtColumn itself
+	ManualUpdatedAt bool `json:"manualUpdatedAt,omitempty"`
+
+	// LockMode locks the selected rows (SELECT ... FOR UPDATE) until the
+	// transaction ends
+	LockMode LockMode `json:"lockMode,omitempty"`
+
+	// Returning lists the columns UpdateData, DeleteData and SoftDelete return,
+	// defaults to all of them
+	Returning []string `json:"returning,omitempty"`
+}
+
+func FindAllAndCount[T any](db Querier, tableName string, options *QueryOptions) (*CountResult[T], error) {
+	return FindAllAndCountContext[T](context.Background(), db, tableName, options)
 }
 
-func FindAllAndCount[T any](db *sql.DB, tableName string, options *QueryOptions) (*CountResult[T], error) {
+// FindAllAndCountContext is like FindAllAndCount but runs under ctx
+func FindAllAndCountContext[T any](ctx context.Context, db Querier, tableName string, options *QueryOptions) (*CountResult[T], error) {
+	ctx, cancel := withDefaultTimeout(ctx)
+	defer cancel()
+
 	var result CountResult[T]
 
-	whereClause, args := buildWhereClause(options)
+	whereClause, args, err := buildWhereClause(options)
+	if err != nil {
+		return nil, err
+	}
 
-	countQuery := fmt.Sprintf("SELECT COUNT(*) FROM %s%s", tableName, whereClause)
-	err := db.QueryRow(countQuery, args...).Scan(&result.Count)
+	countQuery, err := buildCountQuery(tableName, options, whereClause)
+	if err != nil {
+		return nil, err
+	}
+
+	err = runQueryRowContext(ctx, db, countQuery, args...).Scan(&result.Count)
 	if err != nil {
 		return nil, fmt.Errorf("failed to count records: %w", err)
 	}
 
-	selectQuery := buildSelectQuery(tableName, options, whereClause)
-	rows, err := db.Query(selectQuery, args...)
+	selectQuery, err := buildSelectQuery(tableName, options, whereClause)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := runQueryContext(ctx, db, selectQuery, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query records: %w", err)
 	}
@@ -46,11 +104,83 @@ func FindAllAndCount[T any](db *sql.DB, tableName string, options *QueryOptions)
 	return &result, nil
 }
 
-func FindAll[T any](db *sql.DB, tableName string, options *QueryOptions) ([]T, error) {
-	whereClause, args := buildWhereClause(options)
-	query := buildSelectQuery(tableName, options, whereClause)
+func Count[T any](db Querier, tableName string, options *QueryOptions) (int, error) {
+	return CountContext[T](context.Background(), db, tableName, options)
+}
+
+// CountContext is like Count but runs under ctx
+func CountContext[T any](ctx context.Context, db Querier, tableName string, options *QueryOptions) (int, error) {
+	ctx, cancel := withDefaultTimeout(ctx)
+	defer cancel()
+
+	whereClause, args, err := buildWhereClause(options)
+	if err != nil {
+		return 0, err
+	}
+
+	query, err := buildCountQuery(tableName, options, whereClause)
+	if err != nil {
+		return 0, err
+	}
+
+	var count int
+	if err := runQueryRowContext(ctx, db, query, args...).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count records: %w", err)
+	}
+
+	return count, nil
+}
+
+// Exists reports whether any record matches without fetching it
+func Exists(db Querier, tableName string, options *QueryOptions) (bool, error) {
+	return ExistsContext(context.Background(), db, tableName, options)
+}
+
+// ExistsContext is like Exists but runs under ctx
+func ExistsContext(ctx context.Context, db Querier, tableName string, options *QueryOptions) (bool, error) {
+	ctx, cancel := withDefaultTimeout(ctx)
+	defer cancel()
+
+	table, err := validateIdent(tableName)
+	if err != nil {
+		return false, err
+	}
+
+	whereClause, args, err := buildWhereClause(options)
+	if err != nil {
+		return false, err
+	}
+
+	query := fmt.Sprintf("SELECT EXISTS(SELECT 1 FROM %s%s)", table, whereClause)
+
+	var exists bool
+	if err := runQueryRowContext(ctx, db, query, args...).Scan(&exists); err != nil {
+		return false, fmt.Errorf("failed to check existence: %w", err)
+	}
+
+	return exists, nil
+}
+
+func FindAll[T any](db Querier, tableName string, options *QueryOptions) ([]T, error) {
+	return FindAllContext[T](context.Background(), db, tableName, options)
+}
+
+// FindAllContext is like FindAll but runs under ctx
+func FindAllContext[T any](ctx context.Context, db Querier, tableName string, options *QueryOptions) ([]T, error) {
+	ctx, cancel := withDefaultTimeout(ctx)
+	defer cancel()
+
+	whereClause, args, err := buildWhereClause(options)
+	if err != nil {
+		return nil, err
+	}
+
+	query, err := buildSelectQuery(tableName, options, whereClause)
+	if err != nil {
+		return nil, err
+	}
 
-	rows, err := db.Query(query, args...)
+	rows, err := runQueryContext(ctx, db, query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query records: %w", err)
 	}
@@ -59,13 +189,18 @@ func FindAll[T any](db *sql.DB, tableName string, options *QueryOptions) ([]T, e
 	return scanRows[T](rows)
 }
 
-func FindOne[T any](db *sql.DB, tableName string, options *QueryOptions) (*T, error) {
+func FindOne[T any](db Querier, tableName string, options *QueryOptions) (*T, error) {
+	return FindOneContext[T](context.Background(), db, tableName, options)
+}
+
+// FindOneContext is like FindOne but runs under ctx
+func FindOneContext[T any](ctx context.Context, db Querier, tableName string, options *QueryOptions) (*T, error) {
 	if options == nil {
 		options = &QueryOptions{}
 	}
 	options.Limit = 1
 
-	records, err := FindAll[T](db, tableName, options)
+	records, err := FindAllContext[T](ctx, db, tableName, options)
 	if err != nil {
 		return nil, err
 	}
@@ -77,22 +212,124 @@ func FindOne[T any](db *sql.DB, tableName string, options *QueryOptions) (*T, er
 	return &records[0], nil
 }
 
-func FindByPK[T any](db *sql.DB, tableName string, pk interface{}) (*T, error) {
+func FindByPK[T any](db Querier, tableName string, pk interface{}) (*T, error) {
+	return FindByPKContext[T](context.Background(), db, tableName, pk)
+}
+
+// FindByPKContext is like FindByPK but runs under ctx
+func FindByPKContext[T any](ctx context.Context, db Querier, tableName string, pk interface{}) (*T, error) {
 	options := &QueryOptions{
 		Where:     "id = ?",
 		WhereArgs: []interface{}{pk},
 	}
 
-	return FindOne[T](db, tableName, options)
+	return FindOneContext[T](ctx, db, tableName, options)
+}
+
+// FindByPKs fetches the record of a composite primary key, given as column
+// to value, e.g. {"userId": 1, "productId": 2} for a lookup table. FindByPK is
+// the shortcut for tables keyed on id.
+func FindByPKs[T any](db Querier, tableName string, keys map[string]interface{}) (*T, error) {
+	return FindByPKsContext[T](context.Background(), db, tableName, keys)
+}
+
+// FindByPKsContext is like FindByPKs but runs under ctx
+func FindByPKsContext[T any](ctx context.Context, db Querier, tableName string, keys map[string]interface{}) (*T, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("no primary key columns given")
+	}
+
+	// Map order is random, sorting keeps the query text stable
+	columns := make([]string, 0, len(keys))
+	for column := range keys {
+		columns = append(columns, column)
+	}
+	sort.Strings(columns)
+
+	conditions := make([]string, len(columns))
+	args := make([]interface{}, len(columns))
+	for i, column := range columns {
+		col, err := validateIdent(column)
+		if err != nil {
+			return nil, err
+		}
+		conditions[i] = col + " = ?"
+		args[i] = keys[column]
+	}
+
+	options := &QueryOptions{
+		Where:     strings.Join(conditions, " AND "),
+		WhereArgs: args,
+	}
+
+	return FindOneContext[T](ctx, db, tableName, options)
 }
 
-func InsertOne[T any](db *sql.DB, tableName string, payload interface{}) (int64, error) {
+// FindByIDs fetches the records with the given ids in the order of ids.
+// Missing ids are skipped and repeated ids return the record once. No ids
+// returns an empty slice without querying.
+func FindByIDs[T any](db Querier, tableName string, ids []interface{}) ([]T, error) {
+	return FindByIDsContext[T](context.Background(), db, tableName, ids)
+}
+
+// FindByIDsContext is like FindByIDs but runs under ctx
+func FindByIDsContext[T any](ctx context.Context, db Querier, tableName string, ids []interface{}) ([]T, error) {
+	return findByIDs[T](ctx, db, tableName, ids, "")
+}
+
+// findByIDs implements FindByIDs, fetching selectColumns (all when empty)
+func findByIDs[T any](ctx context.Context, db Querier, tableName string, ids []interface{}, selectColumns string) ([]T, error) {
+	if len(ids) == 0 {
+		return []T{}, nil
+	}
+
+	condition, args, err := In("id", ids)
+	if err != nil {
+		return nil, err
+	}
+
+	records, err := FindAllContext[T](ctx, db, tableName, &QueryOptions{Where: condition, WhereArgs: args, Select: selectColumns})
+	if err != nil {
+		return nil, err
+	}
+
+	// Drivers may return the id as another integer type than the caller
+	// passed, so ids are compared by their printed value
+	byID := make(map[string]T, len(records))
+	for i := range records {
+		id, err := columnValue(&records[i], "id")
+		if err != nil {
+			return nil, err
+		}
+		byID[fmt.Sprint(id)] = records[i]
+	}
+
+	ordered := make([]T, 0, len(records))
+	for _, id := range ids {
+		key := fmt.Sprint(id)
+		if record, ok := byID[key]; ok {
+			ordered = append(ordered, record)
+			delete(byID, key)
+		}
+	}
+
+	return ordered, nil
+}
+
+func InsertOne[T any](db Querier, tableName string, payload interface{}) (int64, error) {
+	defer InvalidateTable(tableName)
+
+	table, err := validateIdent(tableName)
+	if err != nil {
+		return 0, err
+	}
+
 	columns, placeholders, values := buildInsertData(payload)
 
 	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)",
-		tableName, strings.Join(columns, ", "), strings.Join(placeholders, ", "))
+		table, strings.Join(columns, ", "), strings.Join(placeholders, ", "))
 
-	result, err := db.Exec(query, values...)
+	result, err := runExec(db, query, values...)
 	if err != nil {
 		return 0, fmt.Errorf("failed to insert record: %w", err)
 	}
@@ -105,45 +342,107 @@ func InsertOne[T any](db *sql.DB, tableName string, payload interface{}) (int64,
 	return lastID, nil
 }
 
-func BulkInsert[T any](db *sql.DB, tableName string, payloads []interface{}) (bool, error) {
+// UpsertOne inserts payload, or updates updateColumns of the existing record
+// when the insert hits a unique key, and reports whether a record was
+// inserted. It uses MySQL's ON DUPLICATE KEY UPDATE, so the unique key of the
+// table decides what counts as the same record.
+func UpsertOne[T any](db Querier, tableName string, payload interface{}, updateColumns []string) (bool, error) {
+	defer InvalidateTable(tableName)
+
+	table, err := validateIdent(tableName)
+	if err != nil {
+		return false, err
+	}
+
+	if len(updateColumns) == 0 {
+		return false, ErrNoFieldsToUpdate
+	}
+
+	updates := make([]string, len(updateColumns))
+	for i, column := range updateColumns {
+		col, err := validateIdent(column)
+		if err != nil {
+			return false, err
+		}
+		updates[i] = fmt.Sprintf("%s = VALUES(%s)", col, col)
+	}
+
+	columns, placeholders, values := buildInsertData(payload)
+
+	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s) ON DUPLICATE KEY UPDATE %s",
+		table, strings.Join(columns, ", "), strings.Join(placeholders, ", "), strings.Join(updates, ", "))
+
+	result, err := runExec(db, query, values...)
+	if err != nil {
+		return false, fmt.Errorf("failed to upsert record: %w", err)
+	}
+
+	// MySQL reports 1 affected row for an insert, 2 for an update and 0 when
+	// the existing record already had these values
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return affected == 1, nil
+}
+
+// BulkInsert inserts every payload, all or nothing: on a *sql.DB it runs in its
+// own transaction, on a *sql.Tx as part of the caller's
+func BulkInsert[T any](db Querier, tableName string, payloads []interface{}) (bool, error) {
+	defer InvalidateTable(tableName)
+
 	if len(payloads) == 0 {
 		return true, nil
 	}
 
-	tx, err := db.Begin()
+	table, err := validateIdent(tableName)
 	if err != nil {
-		return false, fmt.Errorf("failed to begin transaction: %w", err)
+		return false, err
 	}
-	defer tx.Rollback()
 
-	for _, payload := range payloads {
-		columns, placeholders, values := buildInsertData(payload)
+	err = inTransaction(db, func(q Querier) error {
+		for _, payload := range payloads {
+			columns, placeholders, values := buildInsertData(payload)
 
-		query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)",
-			tableName, strings.Join(columns, ", "), strings.Join(placeholders, ", "))
+			query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)",
+				table, strings.Join(columns, ", "), strings.Join(placeholders, ", "))
 
-		_, err := tx.Exec(query, values...)
-		if err != nil {
-			return false, fmt.Errorf("failed to insert record: %w", err)
+			if _, err := runExec(q, query, values...); err != nil {
+				return fmt.Errorf("failed to insert record: %w", err)
+			}
 		}
-	}
-
-	if err := tx.Commit(); err != nil {
-		return false, fmt.Errorf("failed to commit transaction: %w", err)
+		return nil
+	})
+	if err != nil {
+		return false, err
 	}
 
 	return true, nil
 }
 
-func UpdateData[T any](db *sql.DB, tableName string, payload interface{}, options *QueryOptions) ([]T, error) {
-	setClause, setArgs := buildSetClause(payload)
-	whereClause, whereArgs := buildWhereClause(options)
+// UpdateData updates the matching records and returns them, using
+// UPDATE ... RETURNING where the dialect supports it. On MySQL the records are
+// read back by id in the same transaction; UpdateCount saves those queries
+// when only the count is needed.
+func UpdateData[T any](db Querier, tableName string, payload interface{}, options *QueryOptions) ([]T, error) {
+	defer InvalidateTable(tableName)
 
-	args := append(setArgs, whereArgs...)
+	query, args, err := buildUpdateQuery[T](tableName, payload, options)
+	if err != nil {
+		return nil, err
+	}
 
-	query := fmt.Sprintf("UPDATE %s SET %s%s RETURNING *", tableName, setClause, whereClause)
+	if !CurrentDialect.SupportsReturning() {
+		return updateAndReselect[T](db, tableName, query, args, options)
+	}
 
-	rows, err := db.Query(query, args...)
+	returning, err := returningClause(options)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := runQuery(db, query+returning, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to update records: %w", err)
 	}
@@ -152,12 +451,117 @@ func UpdateData[T any](db *sql.DB, tableName string, payload interface{}, option
 	return scanRows[T](rows)
 }
 
-func DeleteData[T any](db *sql.DB, tableName string, options *QueryOptions) ([]T, error) {
-	whereClause, args := buildWhereClause(options)
+// UpdateCount updates the matching records and returns how many were
+// affected. It works on every database, including MySQL.
+func UpdateCount[T any](db Querier, tableName string, payload interface{}, options *QueryOptions) (int64, error) {
+	defer InvalidateTable(tableName)
+
+	query, args, err := buildUpdateQuery[T](tableName, payload, options)
+	if err != nil {
+		return 0, err
+	}
+
+	result, err := runExec(db, query, args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to update records: %w", err)
+	}
+
+	return result.RowsAffected()
+}
+
+// UpdatePatch applies a partial update and returns how many records were
+// affected. patch must be a struct of pointer fields: a nil pointer leaves the
+// column alone and any other pointer sets it, even to the zero value, so a
+// description can be cleared with a pointer to "". It works on MySQL.
+func UpdatePatch[T any](db Querier, tableName string, patch interface{}, options *QueryOptions) (int64, error) {
+	defer InvalidateTable(tableName)
 
-	query := fmt.Sprintf("DELETE FROM %s%s RETURNING *", tableName, whereClause)
+	table, err := validateIdent(tableName)
+	if err != nil {
+		return 0, err
+	}
 
-	rows, err := db.Query(query, args...)
+	setClause, setArgs, err := buildPatchClause(patch)
+	if err != nil {
+		return 0, err
+	}
+	if setClause == "" {
+		return 0, ErrNoFieldsToUpdate
+	}
+
+	if options == nil || !options.ManualUpdatedAt {
+		setClause, setArgs = touchUpdatedAt[T](setClause, setArgs)
+	}
+
+	whereClause, whereArgs, err := buildWhereClause(options)
+	if err != nil {
+		return 0, err
+	}
+
+	query := fmt.Sprintf("UPDATE %s SET %s%s", table, setClause, whereClause)
+
+	result, err := runExec(db, query, append(setArgs, whereArgs...)...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to update records: %w", err)
+	}
+
+	return result.RowsAffected()
+}
+
+// BulkUpdateByIDs applies the same payload to every record whose id is in ids
+// with a single UPDATE ... WHERE id IN (...) and returns how many were affected
+func BulkUpdateByIDs[T any](db Querier, tableName string, payload interface{}, ids []interface{}) (int64, error) {
+	defer InvalidateTable(tableName)
+
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	condition, args, err := In("id", ids)
+	if err != nil {
+		return 0, err
+	}
+
+	query, args, err := buildUpdateQuery[T](tableName, payload, &QueryOptions{
+		Where:     condition,
+		WhereArgs: args,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	// A single statement is atomic on its own, so it runs as it is, and as
+	// part of the transaction when db is a *sql.Tx
+	result, err := runExec(db, query, args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to update records: %w", err)
+	}
+
+	return result.RowsAffected()
+}
+
+// DeleteData deletes the matching records and returns them, using
+// DELETE ... RETURNING where the dialect supports it. On MySQL the records are
+// selected before the delete in the same transaction; DeleteCount saves that
+// query when only the count is needed.
+func DeleteData[T any](db Querier, tableName string, options *QueryOptions) ([]T, error) {
+	defer InvalidateTable(tableName)
+
+	query, args, err := buildDeleteQuery(tableName, options)
+	if err != nil {
+		return nil, err
+	}
+
+	if !CurrentDialect.SupportsReturning() {
+		return selectAndDelete[T](db, tableName, query, args, options)
+	}
+
+	returning, err := returningClause(options)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := runQuery(db, query+returning, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to delete records: %w", err)
 	}
@@ -166,131 +570,457 @@ func DeleteData[T any](db *sql.DB, tableName string, options *QueryOptions) ([]T
 	return scanRows[T](rows)
 }
 
-func buildWhereClause(options *QueryOptions) (string, []interface{}) {
-	if options == nil || options.Where == "" {
-		return "", nil
+// DeleteCount deletes the matching records and returns how many were
+// affected. It works on every database, including MySQL.
+func DeleteCount(db Querier, tableName string, options *QueryOptions) (int64, error) {
+	defer InvalidateTable(tableName)
+
+	query, args, err := buildDeleteQuery(tableName, options)
+	if err != nil {
+		return 0, err
+	}
+
+	result, err := runExec(db, query, args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete records: %w", err)
+	}
+
+	return result.RowsAffected()
+}
+
+// SoftDelete marks the matching records as deleted by setting the soft-delete
+// column to the current time instead of removing them. Like UpdateData it
+// reads the records back by id when the dialect has no RETURNING.
+func SoftDelete[T any](db Querier, tableName string, options *QueryOptions) ([]T, error) {
+	defer InvalidateTable(tableName)
+
+	table, err := validateIdent(tableName)
+	if err != nil {
+		return nil, err
+	}
+
+	if options == nil {
+		options = &QueryOptions{}
+	}
+
+	column := options.SoftDeleteColumn
+	if column == "" {
+		column = DefaultSoftDeleteColumn
+	}
+
+	opts := *options
+	opts.SoftDeleteColumn = column
+	opts.IncludeDeleted = false
+
+	whereClause, args, err := buildWhereClause(&opts)
+	if err != nil {
+		return nil, err
+	}
+
+	query := fmt.Sprintf("UPDATE %s SET %s = NOW()%s", table, opts.SoftDeleteColumn, whereClause)
+
+	if !CurrentDialect.SupportsReturning() {
+		return updateAndReselect[T](db, tableName, query, args, &opts)
+	}
+
+	returning, err := returningClause(&opts)
+	if err != nil {
+		return nil, err
+	}
+	query += returning
+
+	rows, err := runQuery(db, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to soft delete records: %w", err)
+	}
+	defer rows.Close()
+
+	return scanRows[T](rows)
+}
+
+func buildUpdateQuery[T any](tableName string, payload interface{}, options *QueryOptions) (string, []interface{}, error) {
+	table, err := validateIdent(tableName)
+	if err != nil {
+		return "", nil, err
+	}
+
+	setClause, setArgs := buildSetClause(payload)
+	if setClause == "" {
+		return "", nil, ErrNoFieldsToUpdate
+	}
+
+	if options == nil || !options.ManualUpdatedAt {
+		setClause, setArgs = touchUpdatedAt[T](setClause, setArgs)
+	}
+
+	whereClause, whereArgs, err := buildWhereClause(options)
+	if err != nil {
+		return "", nil, err
+	}
+
+	query := fmt.Sprintf("UPDATE %s SET %s%s", table, setClause, whereClause)
+
+	return query, append(setArgs, whereArgs...), nil
+}
+
+func buildDeleteQuery(tableName string, options *QueryOptions) (string, []interface{}, error) {
+	table, err := validateIdent(tableName)
+	if err != nil {
+		return "", nil, err
+	}
+
+	whereClause, args, err := buildWhereClause(options)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return fmt.Sprintf("DELETE FROM %s%s", table, whereClause), args, nil
+}
+
+func buildWhereClause(options *QueryOptions) (string, []interface{}, error) {
+	if options == nil {
+		return "", nil, nil
+	}
+
+	var conditions []string
+	var whereArgs []interface{}
+	where, args := expandSubqueries(options.Where, options.WhereArgs)
+	if where != "" {
+		conditions = append(conditions, where)
+		whereArgs = append(whereArgs, args...)
+	}
+
+	for _, clause := range options.Conditions {
+		expr, args := expandSubqueries(clause.Expr, clause.Args)
+		if strings.TrimSpace(expr) == "" {
+			continue
+		}
+		conditions = append(conditions, expr)
+		whereArgs = append(whereArgs, args...)
+	}
+
+	softDelete := ""
+	if options.SoftDeleteColumn != "" && !options.IncludeDeleted {
+		column, err := validateIdent(options.SoftDeleteColumn)
+		if err != nil {
+			return "", nil, err
+		}
+		softDelete = column + " IS NULL"
+	}
+
+	total := len(conditions)
+	if softDelete != "" {
+		total++
+	}
+
+	switch total {
+	case 0:
+		return "", nil, nil
+	case 1:
+		if softDelete != "" {
+			return " WHERE " + softDelete, nil, nil
+		}
+		return " WHERE " + conditions[0], whereArgs, nil
+	}
+
+	parts := make([]string, 0, total)
+	for _, condition := range conditions {
+		parts = append(parts, "("+condition+")")
+	}
+	if softDelete != "" {
+		parts = append(parts, softDelete)
+	}
+
+	return " WHERE " + strings.Join(parts, " AND "), whereArgs, nil
+}
+
+// returningClause renders the RETURNING clause of the options, validating
+// every column
+func returningClause(options *QueryOptions) (string, error) {
+	columns, err := returningColumns(options)
+	if err != nil {
+		return "", err
 	}
-	return " WHERE " + options.Where, options.WhereArgs
+
+	return " RETURNING " + columns, nil
 }
 
-func buildSelectQuery(tableName string, options *QueryOptions, whereClause string) string {
-	query := fmt.Sprintf("SELECT * FROM %s%s", tableName, whereClause)
+// returningColumns renders the validated Returning list, defaulting to *
+func returningColumns(options *QueryOptions) (string, error) {
+	if options == nil || len(options.Returning) == 0 {
+		return "*", nil
+	}
+
+	columns := make([]string, 0, len(options.Returning))
+	for _, column := range options.Returning {
+		col, err := validateIdent(column)
+		if err != nil {
+			return "", err
+		}
+		columns = append(columns, col)
+	}
+
+	return strings.Join(columns, ", "), nil
+}
+
+func selectKeyword(distinct bool) string {
+	if distinct {
+		return "SELECT DISTINCT"
+	}
+	return "SELECT"
+}
+
+// selectList returns the SELECT list of the options, defaulting to *
+func selectList(options *QueryOptions) string {
+	if options == nil || strings.TrimSpace(options.Select) == "" {
+		return "*"
+	}
+	return options.Select
+}
+
+func buildCountQuery(tableName string, options *QueryOptions, whereClause string) (string, error) {
+	table, err := validateIdent(tableName)
+	if err != nil {
+		return "", err
+	}
+
+	if options != nil && options.Distinct {
+		return fmt.Sprintf("SELECT COUNT(*) FROM (SELECT DISTINCT %s FROM %s%s) AS distinct_rows",
+			selectList(options), table, whereClause), nil
+	}
+
+	return fmt.Sprintf("SELECT COUNT(*) FROM %s%s", table, whereClause), nil
+}
+
+func buildSelectQuery(tableName string, options *QueryOptions, whereClause string) (string, error) {
+	table, err := validateIdent(tableName)
+	if err != nil {
+		return "", err
+	}
+
+	query := fmt.Sprintf("%s %s FROM %s%s", selectKeyword(options != nil && options.Distinct), selectList(options), table, whereClause)
 
 	if options != nil {
-		if options.OrderBy != "" {
-			query += " ORDER BY " + options.OrderBy
+		orderBy, err := renderOrderBy(options.OrderBy, options.Order)
+		if err != nil {
+			return "", err
 		}
 
-		if options.Limit > 0 {
-			query += fmt.Sprintf(" LIMIT %d", options.Limit)
+		if orderBy != "" {
+			query += " ORDER BY " + orderBy
 		}
 
-		if options.Offset > 0 {
-			query += fmt.Sprintf(" OFFSET %d", options.Offset)
+		limitOffset, err := buildLimitOffset(options.Limit, options.Offset)
+		if err != nil {
+			return "", err
 		}
+		query += limitOffset
+
+		lock, err := lockClause(options.LockMode)
+		if err != nil {
+			return "", err
+		}
+		query += lock
 	}
 
-	return query
+	return query, nil
 }
 
 func buildInsertData(payload interface{}) ([]string, []string, []interface{}) {
 	v := reflect.ValueOf(payload)
-	t := reflect.TypeOf(payload)
-
 	if v.Kind() == reflect.Ptr {
 		v = v.Elem()
-		t = t.Elem()
 	}
 
 	var columns []string
 	var placeholders []string
 	var values []interface{}
 
-	for i := 0; i < v.NumField(); i++ {
-		field := v.Field(i)
-		fieldType := t.Field(i)
+	for _, sf := range structFields(v) {
+		field := sf.value
+		fieldType := sf.field
 
 		if !field.CanInterface() {
 			continue
 		}
 
-		dbTag := fieldType.Tag.Get("db")
-		if dbTag == "" || dbTag == "-" {
+		columnName, ok := insertColumn(fieldType)
+		if !ok {
 			continue
 		}
 
-		columnName := strings.Split(dbTag, ",")[0]
-		if columnName == "" {
-			continue
+		columns = append(columns, columnName)
+		placeholders = append(placeholders, "?")
+		values = append(values, bindValue(sf))
+	}
+
+	return columns, placeholders, values
+}
+
+// touchUpdatedAt adds "updatedAt = now" to a SET clause when T has an
+// UpdatedAtColumn that the payload doesn't already set
+func touchUpdatedAt[T any](setClause string, args []interface{}) (string, []interface{}) {
+	if !hasColumn[T](UpdatedAtColumn) {
+		return setClause, args
+	}
+
+	for _, part := range strings.Split(setClause, ", ") {
+		if strings.HasPrefix(part, UpdatedAtColumn+" = ") {
+			return setClause, args
 		}
+	}
 
-		if columnName == "id" || columnName == "createdAt" {
-			continue
+	return setClause + ", " + UpdatedAtColumn + " = ?", append(args, time.Now())
+}
+
+// hasColumn reports whether T has a field mapped to column
+func hasColumn[T any](column string) bool {
+	var record T
+
+	v := reflect.ValueOf(&record).Elem()
+	if v.Kind() != reflect.Struct {
+		return false
+	}
+
+	for _, sf := range structFields(v) {
+		if strings.Split(sf.field.Tag.Get("db"), ",")[0] == column {
+			return true
 		}
+	}
 
-		columns = append(columns, columnName)
-		placeholders = append(placeholders, "?")
-		values = append(values, field.Interface())
+	return false
+}
+
+// insertColumn returns the column a field is written to. Fields tagged
+// insert:"-" (e.g. generated ids and timestamps) are never written; otherwise
+// the insert tag, falling back to the db tag, names the column.
+func insertColumn(field reflect.StructField) (string, bool) {
+	tag := field.Tag.Get("insert")
+	if tag == "-" {
+		return "", false
 	}
 
-	return columns, placeholders, values
+	if tag == "" {
+		tag = field.Tag.Get("db")
+	}
+
+	if tag == "" || tag == "-" {
+		return "", false
+	}
+
+	columnName := strings.Split(tag, ",")[0]
+	if columnName == "" {
+		return "", false
+	}
+
+	return columnName, true
+}
+
+// bindValue returns the value a field is bound as, encoding JSON columns
+func bindValue(sf structField) interface{} {
+	if isJSONField(sf) {
+		return jsonValue{value: sf.value}
+	}
+
+	return sf.value.Interface()
 }
 
 func buildSetClause(payload interface{}) (string, []interface{}) {
 	v := reflect.ValueOf(payload)
-	t := reflect.TypeOf(payload)
-
 	if v.Kind() == reflect.Ptr {
 		v = v.Elem()
-		t = t.Elem()
 	}
 
 	var setParts []string
 	var values []interface{}
 
-	for i := 0; i < v.NumField(); i++ {
-		field := v.Field(i)
-		fieldType := t.Field(i)
+	for _, sf := range structFields(v) {
+		field := sf.value
+		fieldType := sf.field
 
 		if !field.CanInterface() {
 			continue
 		}
 
-		dbTag := fieldType.Tag.Get("db")
-		if dbTag == "" || dbTag == "-" {
+		columnName, ok := insertColumn(fieldType)
+		if !ok {
 			continue
 		}
 
-		columnName := strings.Split(dbTag, ",")[0]
-		if columnName == "" {
+		if field.Kind() == reflect.Ptr && field.IsNil() {
 			continue
 		}
 
-		if columnName == "id" || columnName == "createdAt" {
+		if field.Kind() == reflect.String && field.String() == "" {
 			continue
 		}
 
-		if field.Kind() == reflect.Ptr && field.IsNil() {
+		if (field.Kind() == reflect.Map || field.Kind() == reflect.Slice) && field.IsNil() {
 			continue
 		}
 
-		if field.Kind() == reflect.String && field.String() == "" {
+		setParts = append(setParts, fmt.Sprintf("%s = ?", columnName))
+		values = append(values, bindValue(sf))
+	}
+
+	return strings.Join(setParts, ", "), values
+}
+
+// buildPatchClause renders the SET clause of the non-nil pointer fields of
+// patch, dereferencing them
+func buildPatchClause(patch interface{}) (string, []interface{}, error) {
+	v := reflect.ValueOf(patch)
+	if v.Kind() == reflect.Ptr {
+		v = v.Elem()
+	}
+
+	var setParts []string
+	var values []interface{}
+
+	for _, sf := range structFields(v) {
+		if !sf.value.CanInterface() {
 			continue
 		}
 
-		setParts = append(setParts, fmt.Sprintf("%s = ?", columnName))
-		values = append(values, field.Interface())
+		columnName, ok := insertColumn(sf.field)
+		if !ok {
+			continue
+		}
+
+		if sf.value.Kind() != reflect.Ptr {
+			return "", nil, fmt.Errorf("patch field %s must be a pointer", sf.field.Name)
+		}
+
+		if sf.value.IsNil() {
+			continue
+		}
+
+		column, err := validateIdent(columnName)
+		if err != nil {
+			return "", nil, err
+		}
+
+		setParts = append(setParts, column+" = ?")
+		values = append(values, bindValue(structField{value: sf.value.Elem(), field: sf.field}))
 	}
 
-	return strings.Join(setParts, ", "), values
+	return strings.Join(setParts, ", "), values, nil
 }
 
 func scanRows[T any](rows *sql.Rows) ([]T, error) {
 	var results []T
 
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read columns: %w", err)
+	}
+
+	var zero T
+	fieldIndexes := columnFields(columns, structFields(reflect.ValueOf(&zero).Elem()))
+
 	for rows.Next() {
 		var item T
-		err := scanRow(rows, &item)
+		err := scanRow(rows, fieldIndexes, &item)
 		if err != nil {
 			return nil, err
 		}
@@ -304,43 +1034,177 @@ func scanRows[T any](rows *sql.Rows) ([]T, error) {
 	return results, rows.Err()
 }
 
-func scanRow(scanner interface{}, dest interface{}) error {
-	v := reflect.ValueOf(dest).Elem()
+// structField is a struct field together with its value
+type structField struct {
+	value reflect.Value
+	field reflect.StructField
+}
 
-	fieldCount := v.NumField()
-	scanArgs := make([]interface{}, fieldCount)
+// structFields flattens the fields of a struct, recursing into anonymous
+// embedded structs so a shared base model's fields are treated as the
+// parent's own
+func structFields(v reflect.Value) []structField {
+	var fields []structField
 
-	for i := 0; i < fieldCount; i++ {
-		field := v.Field(i)
-		if field.CanAddr() {
-			scanArgs[i] = field.Addr().Interface()
-		} else {
+	t := v.Type()
+	for i := 0; i < v.NumField(); i++ {
+		field := t.Field(i)
 
-			temp := reflect.New(field.Type())
-			scanArgs[i] = temp.Interface()
+		if field.Anonymous && field.Type.Kind() == reflect.Struct && field.Tag.Get("db") == "" {
+			fields = append(fields, structFields(v.Field(i))...)
+			continue
 		}
+
+		fields = append(fields, structField{value: v.Field(i), field: field})
 	}
 
-	var err error
-	switch s := scanner.(type) {
-	case *sql.Row:
-		err = s.Scan(scanArgs...)
-	case *sql.Rows:
-		err = s.Scan(scanArgs...)
-	default:
-		return fmt.Errorf("unsupported scanner type")
+	return fields
+}
+
+// fieldColumn returns the column a field is read from: the db tag, falling
+// back to the field name. Fields tagged db:"-" are never read.
+func fieldColumn(field reflect.StructField) string {
+	name := strings.Split(field.Tag.Get("db"), ",")[0]
+	if name == "-" {
+		return ""
+	}
+	if name == "" {
+		return field.Name
 	}
+	return name
+}
 
-	if err != nil {
+// columnFields maps each result column to the index of the field it is
+// scanned into, or -1 when there is none, so rows can be scanned by column
+// name whatever the SELECT list and its order. When several columns share a
+// name (SELECT * over a join) the first one wins.
+func columnFields(columns []string, fields []structField) []int {
+	indexes := make([]int, len(columns))
+	used := make([]bool, len(fields))
+
+	for i, column := range columns {
+		indexes[i] = -1
+
+		for j, sf := range fields {
+			if !used[j] && fieldColumn(sf.field) == column {
+				indexes[i] = j
+				break
+			}
+		}
+
+		if indexes[i] == -1 {
+			for j, sf := range fields {
+				if name := fieldColumn(sf.field); !used[j] && name != "" && strings.EqualFold(name, column) {
+					indexes[i] = j
+					break
+				}
+			}
+		}
+
+		if indexes[i] != -1 {
+			used[indexes[i]] = true
+		}
+	}
+
+	return indexes
+}
+
+func scanRow(rows *sql.Rows, fieldIndexes []int, dest interface{}) error {
+	fields := structFields(reflect.ValueOf(dest).Elem())
+	scanArgs := make([]interface{}, len(fieldIndexes))
+
+	for i, index := range fieldIndexes {
+		switch {
+		case index == -1:
+			// Columns without a field are read and discarded
+			scanArgs[i] = new(interface{})
+		case isJSONField(fields[index]) && fields[index].value.CanSet():
+			scanArgs[i] = &jsonColumn{field: fields[index].value}
+		default:
+			scanArgs[i] = scanTarget(fields[index].value)
+		}
+	}
+
+	if err := rows.Scan(scanArgs...); err != nil {
 		return err
 	}
 
-	for i := 0; i < fieldCount; i++ {
-		field := v.Field(i)
-		if !field.CanAddr() {
-			field.Set(reflect.ValueOf(scanArgs[i]).Elem())
+	for i, index := range fieldIndexes {
+		if index != -1 {
+			assignScanned(fields[index].value, scanArgs[i])
 		}
 	}
 
 	return nil
 }
+
+var (
+	timeType    = reflect.TypeOf(time.Time{})
+	scannerType = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+)
+
+// scanTarget returns a destination for database/sql to scan a column into.
+// Plain fields are scanned through sql.Null* intermediaries so NULL columns
+// become zero values instead of scan errors.
+func scanTarget(field reflect.Value) interface{} {
+	if field.CanSet() && field.Addr().Type().Implements(scannerType) {
+		return field.Addr().Interface()
+	}
+
+	if field.Type() == timeType || field.Type() == reflect.PointerTo(timeType) {
+		return &nullTime{}
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		return &sql.NullString{}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &sql.NullInt64{}
+	case reflect.Float32, reflect.Float64:
+		return &sql.NullFloat64{}
+	case reflect.Bool:
+		return &sql.NullBool{}
+	}
+
+	// Pointers (nil on NULL), byte slices and anything else the driver
+	// can convert to directly
+	return reflect.New(field.Type()).Interface()
+}
+
+// assignScanned copies a value scanned by scanTarget into the struct field
+func assignScanned(field reflect.Value, scanned interface{}) {
+	// Unexported fields are skipped and Scanner fields were scanned in place
+	if !field.CanSet() || field.Addr().Interface() == scanned {
+		return
+	}
+
+	switch value := scanned.(type) {
+	case *jsonColumn:
+		// Already decoded into the field
+	case *nullTime:
+		switch {
+		case !value.Valid:
+			field.Set(reflect.Zero(field.Type()))
+		case field.Kind() == reflect.Ptr:
+			t := value.Time
+			field.Set(reflect.ValueOf(&t))
+		default:
+			field.Set(reflect.ValueOf(value.Time))
+		}
+	case *sql.NullString:
+		field.SetString(value.String)
+	case *sql.NullInt64:
+		if field.CanInt() {
+			field.SetInt(value.Int64)
+		} else {
+			field.SetUint(uint64(value.Int64))
+		}
+	case *sql.NullFloat64:
+		field.SetFloat(value.Float64)
+	case *sql.NullBool:
+		field.SetBool(value.Bool)
+	default:
+		field.Set(reflect.ValueOf(scanned).Elem())
+	}
+}
@@ -0,0 +1,96 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// VersionColumn holds the row version used for optimistic locking
+const VersionColumn = "version"
+
+// ErrOptimisticLock is returned when a versioned update matched no rows
+// because the record was modified (or deleted) since it was read
+var ErrOptimisticLock = errors.New("record was modified by another request")
+
+// UpdateWithVersion updates the matching record only if its version still
+// equals the payload's, incrementing the version in the same statement
+func UpdateWithVersion[T any](db Querier, tableName string, payload T, options *QueryOptions) error {
+	defer InvalidateTable(tableName)
+
+	table, err := validateIdent(tableName)
+	if err != nil {
+		return err
+	}
+
+	version, err := columnValue(&payload, VersionColumn)
+	if err != nil {
+		return err
+	}
+
+	setClause, setArgs := buildSetClause(payload)
+	setClause, setArgs = withoutColumn(setClause, setArgs, VersionColumn)
+	if setClause == "" {
+		return ErrNoFieldsToUpdate
+	}
+
+	if options == nil || !options.ManualUpdatedAt {
+		setClause, setArgs = touchUpdatedAt[T](setClause, setArgs)
+	}
+	setClause += fmt.Sprintf(", %s = %s + 1", VersionColumn, VersionColumn)
+
+	opts := QueryOptions{}
+	if options != nil {
+		opts = *options
+	}
+
+	condition := VersionColumn + " = ?"
+	if opts.Where != "" {
+		condition = "(" + opts.Where + ") AND " + condition
+	}
+	opts.Where = condition
+	opts.WhereArgs = append(append([]interface{}{}, opts.WhereArgs...), version)
+
+	whereClause, whereArgs, err := buildWhereClause(&opts)
+	if err != nil {
+		return err
+	}
+
+	query := fmt.Sprintf("UPDATE %s SET %s%s", table, setClause, whereClause)
+
+	result, err := runExec(db, query, append(setArgs, whereArgs...)...)
+	if err != nil {
+		return fmt.Errorf("failed to update record: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if affected == 0 {
+		return ErrOptimisticLock
+	}
+
+	return nil
+}
+
+// withoutColumn drops "column = ?" and its argument from a SET clause
+func withoutColumn(setClause string, args []interface{}, column string) (string, []interface{}) {
+	if setClause == "" {
+		return setClause, args
+	}
+
+	var parts []string
+	var values []interface{}
+
+	for i, part := range strings.Split(setClause, ", ") {
+		if part == column+" = ?" {
+			continue
+		}
+		parts = append(parts, part)
+		values = append(values, args[i])
+	}
+
+	return strings.Join(parts, ", "), values
+}
@@ -2,10 +2,10 @@ package auth
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
-	"strconv"
 	"time"
 
 	"github.com/Jay1570/learning-go/config"
@@ -14,7 +14,20 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 )
 
-const UserKey = "user"
+type contextKey string
+
+const (
+	UserKey   contextKey = "user"
+	UserIDKey contextKey = "userID"
+	RoleKey   contextKey = "role"
+)
+
+// Claims is the payload of the access tokens issued by CreateJWT
+type Claims struct {
+	UserID int    `json:"userID"`
+	Role   string `json:"role,omitempty"`
+	jwt.RegisteredClaims
+}
 
 func WithJWTAuth(next http.Handler, store types.UserStore) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -22,37 +35,38 @@ func WithJWTAuth(next http.Handler, store types.UserStore) http.Handler {
 
 		token, err := validateJWT(tokenString)
 		if err != nil {
-			log.Printf("failed to validate token: %v", err)
+			slog.Debug("failed to validate token", "error", err)
 			permissionDenied(w)
 			return
 		}
 
 		if !token.Valid {
-			log.Println("invalid token")
+			slog.Debug("invalid token")
 			permissionDenied(w)
 			return
 		}
 
-		claims := token.Claims.(jwt.MapClaims)
-		str := claims["userID"].(string)
+		claims := token.Claims.(*Claims)
 
-		userID, err := strconv.Atoi(str)
+		u, err := store.GetUserByID(claims.UserID)
 		if err != nil {
-			log.Printf("failed to convert userID to int: %v", err)
-			permissionDenied(w)
+			slog.Warn("failed to get user by id", "user_id", claims.UserID, "error", err)
+			if errors.Is(err, types.ErrUserNotFound) {
+				unauthorized(w)
+				return
+			}
+			// A failing lookup says nothing about the token, so it is not
+			// reported as a permission problem
+			utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("failed to authenticate user"))
 			return
 		}
 
-		u, err := store.GetUserByID(userID)
-		if err != nil {
-			log.Printf("failed to get user by id: %v", err)
-			permissionDenied(w)
-			return
-		}
-
-		// Add the user to the context
+		// Add the user, its id and its stored role to the context. The role
+		// claim may be stale after a demotion, so it is never trusted.
 		ctx := r.Context()
 		ctx = context.WithValue(ctx, UserKey, u)
+		ctx = context.WithValue(ctx, UserIDKey, u.ID)
+		ctx = context.WithValue(ctx, RoleKey, u.Role)
 		r = r.WithContext(ctx)
 
 		// Call the function if the token is valid
@@ -60,15 +74,34 @@ func WithJWTAuth(next http.Handler, store types.UserStore) http.Handler {
 	})
 }
 
-func CreateJWT(secret string, userID int) (string, error) {
+func CreateJWT(secret string, userID int, role string) (string, error) {
 	expiration := time.Second * time.Duration(config.Envs.JWTExpirationInSeconds)
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"userID":    strconv.Itoa(userID),
-		"expiredAt": time.Now().Add(expiration).Unix(),
-	})
+	method, err := signingMethod()
+	if err != nil {
+		return "", err
+	}
 
-	tokenString, err := token.SignedString([]byte(secret))
+	key, err := signingKey(method, secret)
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	claims := Claims{
+		UserID: userID,
+		Role:   role,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    config.Envs.JWTIssuer,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(expiration)),
+		},
+	}
+	if config.Envs.JWTAudience != "" {
+		claims.Audience = jwt.ClaimStrings{config.Envs.JWTAudience}
+	}
+
+	tokenString, err := jwt.NewWithClaims(method, claims).SignedString(key)
 	if err != nil {
 		return "", err
 	}
@@ -77,20 +110,38 @@ func CreateJWT(secret string, userID int) (string, error) {
 }
 
 func validateJWT(tokenString string) (*jwt.Token, error) {
-	return jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
+	method, err := signingMethod()
+	if err != nil {
+		return nil, err
+	}
 
-		return []byte(config.Envs.JWTSecret), nil
-	})
+	// Only the configured algorithm is accepted, so a token can't pick how it
+	// gets verified (e.g. HS256 signed with the RSA public key)
+	options := []jwt.ParserOption{jwt.WithValidMethods([]string{method.Alg()})}
+
+	// Tokens of another deployment sharing the key are rejected by issuer
+	// and audience
+	if config.Envs.JWTIssuer != "" {
+		options = append(options, jwt.WithIssuer(config.Envs.JWTIssuer))
+	}
+	if config.Envs.JWTAudience != "" {
+		options = append(options, jwt.WithAudience(config.Envs.JWTAudience))
+	}
+
+	return jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
+		return verificationKey(method)
+	}, options...)
 }
 
 func permissionDenied(w http.ResponseWriter) {
-	utils.WriteError(w, http.StatusForbidden, fmt.Errorf("permission denied"))
+	utils.WriteAPIError(w, utils.Forbidden("permission denied"))
 }
 
-func GetUserIDFromContext(ctx context.Context) *types.User {
+func unauthorized(w http.ResponseWriter) {
+	utils.WriteAPIError(w, utils.Unauthorized("unauthorized"))
+}
+
+func GetUserFromContext(ctx context.Context) *types.User {
 	user, ok := ctx.Value(UserKey).(*types.User)
 	if !ok {
 		return nil
@@ -98,3 +149,8 @@ func GetUserIDFromContext(ctx context.Context) *types.User {
 
 	return user
 }
+
+func GetUserIDFromContext(ctx context.Context) (int, bool) {
+	userID, ok := ctx.Value(UserIDKey).(int)
+	return userID, ok
+}
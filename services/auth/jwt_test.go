@@ -0,0 +1,101 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Jay1570/learning-go/config"
+	"github.com/Jay1570/learning-go/testutil"
+	"github.com/Jay1570/learning-go/types"
+)
+
+func TestJWTIssuerAndAudience(t *testing.T) {
+	defer func(cfg config.Config) { config.Envs = cfg }(config.Envs)
+	config.Envs.JWTSecret = "test-secret"
+	config.Envs.JWTIssuer = "deployment-a"
+	config.Envs.JWTAudience = "api-a"
+
+	token, err := CreateJWT(config.Envs.JWTSecret, 1, "user")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("should accept a token of this deployment", func(t *testing.T) {
+		if _, err := validateJWT(token); err != nil {
+			t.Errorf("expected a valid token, got %v", err)
+		}
+	})
+
+	t.Run("should reject a token with another issuer", func(t *testing.T) {
+		defer func(issuer string) { config.Envs.JWTIssuer = issuer }(config.Envs.JWTIssuer)
+		config.Envs.JWTIssuer = "deployment-b"
+
+		if _, err := validateJWT(token); err == nil {
+			t.Error("expected the issuer to be rejected")
+		}
+	})
+
+	t.Run("should reject a token for another audience", func(t *testing.T) {
+		defer func(audience string) { config.Envs.JWTAudience = audience }(config.Envs.JWTAudience)
+		config.Envs.JWTAudience = "api-b"
+
+		if _, err := validateJWT(token); err == nil {
+			t.Error("expected the audience to be rejected")
+		}
+	})
+}
+
+func TestWithJWTAuth(t *testing.T) {
+	defer func(cfg config.Config) { config.Envs = cfg }(config.Envs)
+	config.Envs.JWTSecret = "test-secret"
+
+	store := testutil.NewUserStore(types.User{ID: 1, Email: "demoted@example.com", Role: types.RoleUser})
+	admin := RequireRole(types.RoleAdmin)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	t.Run("should take the role from the stored user, not the token", func(t *testing.T) {
+		// Issued while the user was still an admin
+		token, err := CreateJWT(config.Envs.JWTSecret, 1, types.RoleAdmin)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req := httptest.NewRequest(http.MethodGet, "/admin", nil)
+		req.Header.Set("Authorization", token)
+		rr := httptest.NewRecorder()
+
+		WithJWTAuth(admin, store).ServeHTTP(rr, req)
+
+		if rr.Code != http.StatusForbidden {
+			t.Errorf("expexted status code %d, got %d", http.StatusForbidden, rr.Code)
+		}
+	})
+	t.Run("should fail with 500 when the user lookup fails", func(t *testing.T) {
+		token, err := CreateJWT(config.Envs.JWTSecret, 1, types.RoleUser)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req := httptest.NewRequest(http.MethodGet, "/admin", nil)
+		req.Header.Set("Authorization", token)
+		rr := httptest.NewRecorder()
+
+		WithJWTAuth(admin, failingUserStore{store}).ServeHTTP(rr, req)
+
+		if rr.Code != http.StatusInternalServerError {
+			t.Errorf("expexted status code %d, got %d", http.StatusInternalServerError, rr.Code)
+		}
+	})
+}
+
+// failingUserStore fails every lookup by id, as a lost database connection would
+type failingUserStore struct {
+	types.UserStore
+}
+
+func (failingUserStore) GetUserByID(id int) (*types.User, error) {
+	return nil, errors.New("connection refused")
+}
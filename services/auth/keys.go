@@ -0,0 +1,102 @@
+package auth
+
+import (
+	"crypto/rsa"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/Jay1570/learning-go/config"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var (
+	rsaKeysOnce sync.Once
+	rsaPrivate  *rsa.PrivateKey
+	rsaPublic   *rsa.PublicKey
+	rsaKeysErr  error
+)
+
+// signingMethod returns the method selected by config.Envs.JWTAlgorithm
+func signingMethod() (jwt.SigningMethod, error) {
+	switch config.Envs.JWTAlgorithm {
+	case "", "HS256":
+		return jwt.SigningMethodHS256, nil
+	case "RS256":
+		return jwt.SigningMethodRS256, nil
+	default:
+		return nil, fmt.Errorf("unsupported JWT algorithm: %s", config.Envs.JWTAlgorithm)
+	}
+}
+
+// signingKey returns the key tokens are signed with: the shared secret for
+// HS256 or the RSA private key for RS256
+func signingKey(method jwt.SigningMethod, secret string) (interface{}, error) {
+	if method != jwt.SigningMethodRS256 {
+		return []byte(secret), nil
+	}
+
+	if err := loadRSAKeys(); err != nil {
+		return nil, err
+	}
+	if rsaPrivate == nil {
+		return nil, fmt.Errorf("JWT_PRIVATE_KEY_PATH is required to sign RS256 tokens")
+	}
+
+	return rsaPrivate, nil
+}
+
+// verificationKey returns the key tokens are verified with: the shared secret
+// for HS256 or the RSA public key for RS256
+func verificationKey(method jwt.SigningMethod) (interface{}, error) {
+	if method != jwt.SigningMethodRS256 {
+		return []byte(config.Envs.JWTSecret), nil
+	}
+
+	if err := loadRSAKeys(); err != nil {
+		return nil, err
+	}
+	if rsaPublic == nil {
+		return nil, fmt.Errorf("JWT_PUBLIC_KEY_PATH is required to verify RS256 tokens")
+	}
+
+	return rsaPublic, nil
+}
+
+// loadRSAKeys reads the PEM encoded keys from config once. Either key may be
+// missing, e.g. a verifier only holds the public key.
+func loadRSAKeys() error {
+	rsaKeysOnce.Do(func() {
+		if path := config.Envs.JWTPrivateKeyPath; path != "" {
+			pem, err := os.ReadFile(path)
+			if err != nil {
+				rsaKeysErr = fmt.Errorf("failed to read JWT private key: %w", err)
+				return
+			}
+
+			rsaPrivate, err = jwt.ParseRSAPrivateKeyFromPEM(pem)
+			if err != nil {
+				rsaKeysErr = fmt.Errorf("failed to parse JWT private key: %w", err)
+				return
+			}
+		}
+
+		if path := config.Envs.JWTPublicKeyPath; path != "" {
+			pem, err := os.ReadFile(path)
+			if err != nil {
+				rsaKeysErr = fmt.Errorf("failed to read JWT public key: %w", err)
+				return
+			}
+
+			rsaPublic, err = jwt.ParseRSAPublicKeyFromPEM(pem)
+			if err != nil {
+				rsaKeysErr = fmt.Errorf("failed to parse JWT public key: %w", err)
+				return
+			}
+		} else if rsaPrivate != nil {
+			rsaPublic = &rsaPrivate.PublicKey
+		}
+	})
+
+	return rsaKeysErr
+}
@@ -1,9 +1,18 @@
 package auth
 
-import "golang.org/x/crypto/bcrypt"
+import (
+	"github.com/Jay1570/learning-go/config"
+	"golang.org/x/crypto/bcrypt"
+)
 
 func HashPassword(password string) (string, error) {
-	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	return HashPasswordWithCost(password, int(config.Envs.BcryptCost))
+}
+
+// HashPasswordWithCost hashes with an explicit bcrypt cost, clamped into the
+// range bcrypt accepts
+func HashPasswordWithCost(password string, cost int) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), clampCost(cost))
 	if err != nil {
 		return "", err
 	}
@@ -11,6 +20,16 @@ func HashPassword(password string) (string, error) {
 	return string(hash), nil
 }
 
+func clampCost(cost int) int {
+	if cost < bcrypt.MinCost {
+		return bcrypt.MinCost
+	}
+	if cost > bcrypt.MaxCost {
+		return bcrypt.MaxCost
+	}
+	return cost
+}
+
 func ComparePasswords(hashed string, plain string) bool {
 	err := bcrypt.CompareHashAndPassword([]byte(hashed), []byte(plain))
 	return err == nil
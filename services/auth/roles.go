@@ -0,0 +1,28 @@
+package auth
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+)
+
+// RequireRole only lets requests through whose token carries the given role.
+// It has to run inside WithJWTAuth, which puts the role in the context.
+func RequireRole(role string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if GetRoleFromContext(r.Context()) != role {
+				slog.Debug("role required", "role", role)
+				permissionDenied(w)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func GetRoleFromContext(ctx context.Context) string {
+	role, _ := ctx.Value(RoleKey).(string)
+	return role
+}
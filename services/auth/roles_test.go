@@ -0,0 +1,51 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Jay1570/learning-go/types"
+)
+
+func TestRequireRole(t *testing.T) {
+	handler := RequireRole(types.RoleAdmin)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	t.Run("should allow a matching role", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/products", nil)
+		req = req.WithContext(context.WithValue(req.Context(), RoleKey, types.RoleAdmin))
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		if rr.Code != http.StatusOK {
+			t.Errorf("expexted status code %d, got %d", http.StatusOK, rr.Code)
+		}
+	})
+
+	t.Run("should forbid a different role", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/products", nil)
+		req = req.WithContext(context.WithValue(req.Context(), RoleKey, types.RoleUser))
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		if rr.Code != http.StatusForbidden {
+			t.Errorf("expexted status code %d, got %d", http.StatusForbidden, rr.Code)
+		}
+	})
+
+	t.Run("should forbid a request without a role", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/products", nil)
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		if rr.Code != http.StatusForbidden {
+			t.Errorf("expexted status code %d, got %d", http.StatusForbidden, rr.Code)
+		}
+	})
+}
@@ -1,14 +1,27 @@
 package logging
 
 import (
-	"log"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"io"
+	"log/slog"
 	"net/http"
+	"strings"
 	"time"
 )
 
+type contextKey string
+
+const (
+	RequestIDHeader            = "X-Request-ID"
+	RequestIDKey    contextKey = "requestID"
+)
+
 type wrappedWriter struct {
 	http.ResponseWriter
-	statusCode int
+	statusCode   int
+	bytesWritten int
 }
 
 func (w *wrappedWriter) WriteHeader(statusCode int) {
@@ -16,15 +29,72 @@ func (w *wrappedWriter) WriteHeader(statusCode int) {
 	w.statusCode = statusCode
 }
 
+func (w *wrappedWriter) Write(b []byte) (int, error) {
+	n, err := w.ResponseWriter.Write(b)
+	w.bytesWritten += n
+	return n, err
+}
+
+// NewLogger builds the JSON logger shared by the whole app. level is one of
+// debug, info, warn or error; anything else falls back to info.
+func NewLogger(w io.Writer, level string) *slog.Logger {
+	return slog.New(slog.NewJSONHandler(w, &slog.HandlerOptions{Level: ParseLevel(level)}))
+}
+
+func ParseLevel(level string) slog.Level {
+	switch strings.ToLower(strings.TrimSpace(level)) {
+	case "debug":
+		return slog.LevelDebug
+	case "warn", "warning":
+		return slog.LevelWarn
+	case "error":
+		return slog.LevelError
+	default:
+		return slog.LevelInfo
+	}
+}
+
 func Logging(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
 
+		requestID := r.Header.Get(RequestIDHeader)
+		if requestID == "" {
+			requestID = newRequestID()
+		}
+		w.Header().Set(RequestIDHeader, requestID)
+		r = r.WithContext(context.WithValue(r.Context(), RequestIDKey, requestID))
+
 		wrapped := &wrappedWriter{
 			ResponseWriter: w,
 			statusCode:     http.StatusOK,
 		}
 		next.ServeHTTP(wrapped, r)
-		log.Println(wrapped.statusCode, r.Method, r.URL.Path, time.Since(start))
+		slog.LogAttrs(r.Context(), slog.LevelInfo, "request",
+			slog.String("request_id", requestID),
+			slog.String("method", r.Method),
+			slog.String("path", r.URL.Path),
+			slog.Int("status", wrapped.statusCode),
+			slog.Int("bytes", wrapped.bytesWritten),
+			slog.Duration("duration", time.Since(start)),
+		)
 	})
 }
+
+func GetRequestID(ctx context.Context) string {
+	requestID, ok := ctx.Value(RequestIDKey).(string)
+	if !ok {
+		return ""
+	}
+
+	return requestID
+}
+
+func newRequestID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return ""
+	}
+
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,86 @@
+package mail
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/smtp"
+	"strings"
+
+	"github.com/Jay1570/learning-go/config"
+	"github.com/Jay1570/learning-go/types"
+)
+
+// ErrNoSender is returned outside development when no SMTP server is
+// configured
+var ErrNoSender = errors.New("no email sender configured: set SMTP_HOST and SMTP_FROM")
+
+// NewSender picks the sender for the environment: SMTP when SMTP_HOST is set,
+// otherwise LogSender in development only. Anywhere else the API refuses to
+// start rather than drop verification and password reset emails.
+func NewSender() (types.EmailSender, error) {
+	if config.Envs.SMTPHost != "" {
+		if config.Envs.SMTPFrom == "" {
+			return nil, ErrNoSender
+		}
+		return NewSMTPSender(config.Envs.SMTPHost, config.Envs.SMTPPort, config.Envs.SMTPUsername,
+			config.Envs.SMTPPassword, config.Envs.SMTPFrom), nil
+	}
+
+	if config.Envs.Environment == "development" {
+		return NewLogSender(), nil
+	}
+
+	return nil, ErrNoSender
+}
+
+// LogSender "sends" emails by logging them. It is only used in development
+// when no SMTP server is configured.
+type LogSender struct{}
+
+func NewLogSender() *LogSender {
+	return &LogSender{}
+}
+
+// SendEmail logs the recipient and subject only. The body carries
+// verification and password reset tokens, which must never reach the logs.
+func (s *LogSender) SendEmail(to, subject, body string) error {
+	slog.Info("email", "to", to, "subject", subject)
+	return nil
+}
+
+// SMTPSender delivers emails through an SMTP server, authenticating when a
+// username is set
+type SMTPSender struct {
+	addr string
+	auth smtp.Auth
+	from string
+}
+
+func NewSMTPSender(host, port, username, password, from string) *SMTPSender {
+	var auth smtp.Auth
+	if username != "" {
+		auth = smtp.PlainAuth("", username, password, host)
+	}
+
+	return &SMTPSender{addr: net.JoinHostPort(host, port), auth: auth, from: from}
+}
+
+func (s *SMTPSender) SendEmail(to, subject, body string) error {
+	if strings.ContainsAny(to+subject, "\r\n") {
+		return fmt.Errorf("invalid email header")
+	}
+
+	msg := "From: " + s.from + "\r\n" +
+		"To: " + to + "\r\n" +
+		"Subject: " + subject + "\r\n" +
+		"Content-Type: text/plain; charset=UTF-8\r\n" +
+		"\r\n" + body
+
+	if err := smtp.SendMail(s.addr, s.auth, s.from, []string{to}, []byte(msg)); err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+
+	return nil
+}
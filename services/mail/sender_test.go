@@ -0,0 +1,56 @@
+package mail
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Jay1570/learning-go/config"
+)
+
+func TestNewSender(t *testing.T) {
+	defer func(env, host, from string) {
+		config.Envs.Environment, config.Envs.SMTPHost, config.Envs.SMTPFrom = env, host, from
+	}(config.Envs.Environment, config.Envs.SMTPHost, config.Envs.SMTPFrom)
+
+	t.Run("should use the log sender in development without smtp", func(t *testing.T) {
+		config.Envs.Environment, config.Envs.SMTPHost = "development", ""
+
+		sender, err := NewSender()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := sender.(*LogSender); !ok {
+			t.Errorf("expected *LogSender, got %T", sender)
+		}
+	})
+
+	t.Run("should refuse to fall back to logging outside development", func(t *testing.T) {
+		for _, env := range []string{"", "production", "staging"} {
+			config.Envs.Environment, config.Envs.SMTPHost = env, ""
+
+			if _, err := NewSender(); !errors.Is(err, ErrNoSender) {
+				t.Errorf("APP_ENV=%q: expected ErrNoSender, got %v", env, err)
+			}
+		}
+	})
+
+	t.Run("should use smtp when it is configured", func(t *testing.T) {
+		config.Envs.Environment, config.Envs.SMTPHost, config.Envs.SMTPFrom = "production", "smtp.example.com", "noreply@example.com"
+
+		sender, err := NewSender()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := sender.(*SMTPSender); !ok {
+			t.Errorf("expected *SMTPSender, got %T", sender)
+		}
+	})
+
+	t.Run("should reject smtp without a from address", func(t *testing.T) {
+		config.Envs.SMTPHost, config.Envs.SMTPFrom = "smtp.example.com", ""
+
+		if _, err := NewSender(); !errors.Is(err, ErrNoSender) {
+			t.Errorf("expected ErrNoSender, got %v", err)
+		}
+	})
+}
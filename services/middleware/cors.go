@@ -0,0 +1,108 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// ErrCORSWildcardCredentials is returned for credentialed CORS with the "*"
+// origin, which browsers refuse and which would let any site act as the user
+var ErrCORSWildcardCredentials = errors.New("cors: credentials can't be allowed for the wildcard origin")
+
+type CORSOptions struct {
+	AllowedOrigins []string
+	AllowedMethods []string
+	AllowedHeaders []string
+
+	// AllowCredentials lets browsers send cookies, needed for the cookie auth
+	// mode. It requires an explicit AllowedOrigins list.
+	AllowCredentials bool
+}
+
+// Validate rejects options browsers would refuse
+func (o CORSOptions) Validate() error {
+	if o.AllowCredentials && slices.Contains(o.AllowedOrigins, "*") {
+		return ErrCORSWildcardCredentials
+	}
+
+	return nil
+}
+
+// CORSGroup applies its own options to the paths under PathPrefix
+type CORSGroup struct {
+	PathPrefix string
+	Options    CORSOptions
+}
+
+// CORS answers preflight requests and sets the CORS headers. A request uses
+// the options of the first group whose prefix matches its path, falling back
+// to options.
+func CORS(next http.Handler, options CORSOptions, groups ...CORSGroup) (http.Handler, error) {
+	if err := options.Validate(); err != nil {
+		return nil, err
+	}
+
+	fallback := corsHandler(next, options)
+
+	handlers := make([]http.Handler, len(groups))
+	for i, group := range groups {
+		if err := group.Options.Validate(); err != nil {
+			return nil, err
+		}
+		handlers[i] = corsHandler(next, group.Options)
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for i, group := range groups {
+			if r.URL.Path == group.PathPrefix || strings.HasPrefix(r.URL.Path, strings.TrimSuffix(group.PathPrefix, "/")+"/") {
+				handlers[i].ServeHTTP(w, r)
+				return
+			}
+		}
+
+		fallback.ServeHTTP(w, r)
+	}), nil
+}
+
+func corsHandler(next http.Handler, options CORSOptions) http.Handler {
+	methods := strings.Join(options.AllowedMethods, ", ")
+	headers := strings.Join(options.AllowedHeaders, ", ")
+	allowAll := slices.Contains(options.AllowedOrigins, "*")
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Add("Vary", "Origin")
+
+		if !allowAll && !slices.Contains(options.AllowedOrigins, origin) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if allowAll {
+			w.Header().Set("Access-Control-Allow-Origin", "*")
+		} else {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+		}
+
+		if options.AllowCredentials {
+			w.Header().Set("Access-Control-Allow-Credentials", "true")
+		}
+
+		// Preflight requests are answered here and never reach the router
+		if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+			w.Header().Set("Access-Control-Allow-Methods", methods)
+			w.Header().Set("Access-Control-Allow-Headers", headers)
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
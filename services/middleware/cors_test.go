@@ -0,0 +1,73 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCORS(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	public := CORSOptions{AllowedOrigins: []string{"*"}, AllowedMethods: []string{"GET"}}
+	credentialed := CORSOptions{
+		AllowedOrigins:   []string{"https://shop.example.com"},
+		AllowedMethods:   []string{"GET", "POST"},
+		AllowCredentials: true,
+	}
+
+	handler, err := CORS(next, public, CORSGroup{PathPrefix: "/api/v1/me", Options: credentialed})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	send := func(path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Origin", "https://shop.example.com")
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	t.Run("should allow credentials for the group's origins", func(t *testing.T) {
+		rr := send("/api/v1/me")
+
+		if rr.Header().Get("Access-Control-Allow-Origin") != "https://shop.example.com" {
+			t.Errorf("expected the origin to be echoed, got %q", rr.Header().Get("Access-Control-Allow-Origin"))
+		}
+		if rr.Header().Get("Access-Control-Allow-Credentials") != "true" {
+			t.Error("expected credentials to be allowed")
+		}
+	})
+
+	t.Run("should use the global options outside the groups", func(t *testing.T) {
+		rr := send("/api/v1/products")
+
+		if rr.Header().Get("Access-Control-Allow-Origin") != "*" {
+			t.Errorf("expected the wildcard origin, got %q", rr.Header().Get("Access-Control-Allow-Origin"))
+		}
+		if rr.Header().Get("Access-Control-Allow-Credentials") != "" {
+			t.Error("expected no credentials header")
+		}
+	})
+
+	t.Run("should not match a path that only shares the prefix", func(t *testing.T) {
+		rr := send("/api/v1/members")
+
+		if rr.Header().Get("Access-Control-Allow-Credentials") != "" {
+			t.Error("expected no credentials header")
+		}
+	})
+
+	t.Run("should reject credentials with the wildcard origin", func(t *testing.T) {
+		wildcard := CORSOptions{AllowedOrigins: []string{"*"}, AllowCredentials: true}
+
+		if _, err := CORS(next, public, CORSGroup{PathPrefix: "/login", Options: wildcard}); !errors.Is(err, ErrCORSWildcardCredentials) {
+			t.Errorf("expected ErrCORSWildcardCredentials, got %v", err)
+		}
+	})
+}
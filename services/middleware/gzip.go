@@ -0,0 +1,146 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// compressedTypes are content types that are compressed already, gzipping
+// them again costs CPU without saving bytes
+var compressedTypes = []string{"image/", "video/", "audio/", "application/zip", "application/gzip", "application/x-gzip", "font/woff"}
+
+// Gzip compresses responses for clients accepting gzip. Responses smaller
+// than minSize bytes are sent as they are, since the gzip framing would eat
+// most of the saving.
+func Gzip(next http.Handler, minSize int) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept-Encoding")
+
+		if r.Method == http.MethodHead || !acceptsGzip(r.Header.Get("Accept-Encoding")) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// Not deferred: on a panic the buffered response is dropped, so
+		// Recovery can still send its error
+		gw := &gzipResponseWriter{ResponseWriter: w, minSize: minSize, status: http.StatusOK}
+		next.ServeHTTP(gw, r)
+		gw.close()
+	})
+}
+
+// acceptsGzip reports whether an Accept-Encoding header allows gzip
+func acceptsGzip(header string) bool {
+	for _, part := range strings.Split(header, ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if coding != "gzip" && coding != "*" {
+			continue
+		}
+
+		// q=0 means "not acceptable"
+		if q, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if weight, err := strconv.ParseFloat(q, 64); err == nil && weight == 0 {
+				return false
+			}
+		}
+
+		return true
+	}
+
+	return false
+}
+
+// gzipResponseWriter holds the response back until minSize bytes are written
+// or the handler returns, then decides whether to compress it
+type gzipResponseWriter struct {
+	http.ResponseWriter
+	minSize int
+	status  int
+	buf     []byte
+	gz      *gzip.Writer
+	started bool
+}
+
+func (w *gzipResponseWriter) WriteHeader(status int) {
+	if !w.started {
+		w.status = status
+	}
+}
+
+func (w *gzipResponseWriter) Write(b []byte) (int, error) {
+	if w.started {
+		if w.gz != nil {
+			return w.gz.Write(b)
+		}
+		return w.ResponseWriter.Write(b)
+	}
+
+	w.buf = append(w.buf, b...)
+	if len(w.buf) >= w.minSize {
+		if err := w.start(); err != nil {
+			return 0, err
+		}
+	}
+
+	return len(b), nil
+}
+
+// start sends the headers and the buffered body, compressed when worth it
+func (w *gzipResponseWriter) start() error {
+	w.started = true
+
+	header := w.Header()
+	if header.Get("Content-Type") == "" && len(w.buf) > 0 {
+		// Sniff now, the writer below would sniff the compressed bytes
+		header.Set("Content-Type", http.DetectContentType(w.buf))
+	}
+
+	if w.shouldCompress() {
+		header.Set("Content-Encoding", "gzip")
+		header.Del("Content-Length")
+		w.gz = gzip.NewWriter(w.ResponseWriter)
+	}
+
+	w.ResponseWriter.WriteHeader(w.status)
+
+	var err error
+	if w.gz != nil {
+		_, err = w.gz.Write(w.buf)
+	} else if len(w.buf) > 0 {
+		_, err = w.ResponseWriter.Write(w.buf)
+	}
+	w.buf = nil
+
+	return err
+}
+
+func (w *gzipResponseWriter) shouldCompress() bool {
+	if len(w.buf) < w.minSize || w.status == http.StatusNoContent || w.status == http.StatusNotModified {
+		return false
+	}
+
+	if w.Header().Get("Content-Encoding") != "" {
+		return false
+	}
+
+	contentType := w.Header().Get("Content-Type")
+	for _, compressed := range compressedTypes {
+		if strings.HasPrefix(contentType, compressed) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (w *gzipResponseWriter) close() {
+	if !w.started {
+		w.start()
+	}
+
+	if w.gz != nil {
+		w.gz.Close()
+	}
+}
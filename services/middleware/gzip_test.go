@@ -0,0 +1,84 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestGzip(t *testing.T) {
+	body := strings.Repeat(`{"name":"chair"}`, 100)
+
+	send := func(acceptEncoding, contentType, body string) *httptest.ResponseRecorder {
+		handler := Gzip(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", contentType)
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(body))
+		}), 1024)
+
+		req := httptest.NewRequest(http.MethodGet, "/products", nil)
+		if acceptEncoding != "" {
+			req.Header.Set("Accept-Encoding", acceptEncoding)
+		}
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	t.Run("should compress a large response", func(t *testing.T) {
+		rr := send("gzip, deflate", "application/json", body)
+
+		if rr.Header().Get("Content-Encoding") != "gzip" {
+			t.Fatalf("expected a gzip response, got %q", rr.Header().Get("Content-Encoding"))
+		}
+
+		reader, err := gzip.NewReader(rr.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		decoded, err := io.ReadAll(reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if string(decoded) != body {
+			t.Errorf("expected the original body after decompressing, got %d bytes", len(decoded))
+		}
+	})
+
+	t.Run("should not compress without Accept-Encoding", func(t *testing.T) {
+		rr := send("", "application/json", body)
+
+		if rr.Header().Get("Content-Encoding") != "" || rr.Body.String() != body {
+			t.Errorf("expected the plain body")
+		}
+	})
+
+	t.Run("should not compress when gzip is refused", func(t *testing.T) {
+		rr := send("gzip;q=0", "application/json", body)
+
+		if rr.Header().Get("Content-Encoding") != "" {
+			t.Errorf("expected no compression, got %q", rr.Header().Get("Content-Encoding"))
+		}
+	})
+
+	t.Run("should not compress a small response", func(t *testing.T) {
+		rr := send("gzip", "application/json", `{"status":200}`)
+
+		if rr.Header().Get("Content-Encoding") != "" || rr.Body.String() != `{"status":200}` {
+			t.Errorf("expected the plain body, got %q", rr.Body.String())
+		}
+	})
+
+	t.Run("should not compress compressed content", func(t *testing.T) {
+		rr := send("gzip", "image/png", body)
+
+		if rr.Header().Get("Content-Encoding") != "" {
+			t.Errorf("expected no compression, got %q", rr.Header().Get("Content-Encoding"))
+		}
+	})
+}
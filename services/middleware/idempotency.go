@@ -0,0 +1,247 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/Jay1570/learning-go/utils"
+)
+
+const (
+	IdempotencyKeyHeader      = "Idempotency-Key"
+	IdempotentReplayedHeader  = "Idempotent-Replayed"
+	maxIdempotencyKeyLength   = 255
+	idempotencySweepFrequency = time.Minute
+)
+
+// CachedResponse is a response recorded for an idempotency key
+type CachedResponse struct {
+	Status int
+	Header http.Header
+	Body   []byte
+}
+
+// IdempotencyEntry is what a store holds for a key: the hash of the request
+// body that claimed it and, once that request is done, its response
+type IdempotencyEntry struct {
+	RequestHash string
+	Response    *CachedResponse // nil while the first request is running
+}
+
+// IdempotencyStore keeps the first response of every idempotency key for a
+// while. The in-memory default is MemoryIdempotencyStore; a shared store is
+// needed once the API runs on several instances.
+type IdempotencyStore interface {
+	// Reserve claims a free key for a request. A taken key is not claimed
+	// and its entry is returned instead.
+	Reserve(key, requestHash string, ttl time.Duration) (*IdempotencyEntry, bool)
+	// Complete stores the response of a reserved key
+	Complete(key string, response *CachedResponse, ttl time.Duration)
+	// Release frees a reserved key so a retry runs again
+	Release(key string)
+}
+
+type idempotencyEntry struct {
+	entry     IdempotencyEntry
+	expiresAt time.Time
+}
+
+// MemoryIdempotencyStore is an in-process IdempotencyStore
+type MemoryIdempotencyStore struct {
+	mu        sync.Mutex
+	entries   map[string]idempotencyEntry
+	lastSweep time.Time
+}
+
+func NewMemoryIdempotencyStore() *MemoryIdempotencyStore {
+	return &MemoryIdempotencyStore{
+		entries:   make(map[string]idempotencyEntry),
+		lastSweep: time.Now(),
+	}
+}
+
+func (s *MemoryIdempotencyStore) Reserve(key, requestHash string, ttl time.Duration) (*IdempotencyEntry, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.sweep(now)
+
+	if stored, ok := s.entries[key]; ok && !now.After(stored.expiresAt) {
+		entry := stored.entry
+		return &entry, false
+	}
+
+	s.entries[key] = idempotencyEntry{entry: IdempotencyEntry{RequestHash: requestHash}, expiresAt: now.Add(ttl)}
+	return nil, true
+}
+
+func (s *MemoryIdempotencyStore) Complete(key string, response *CachedResponse, ttl time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored := s.entries[key]
+	stored.entry.Response = response
+	stored.expiresAt = time.Now().Add(ttl)
+	s.entries[key] = stored
+}
+
+func (s *MemoryIdempotencyStore) Release(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.entries, key)
+}
+
+// sweep drops expired entries
+func (s *MemoryIdempotencyStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < idempotencySweepFrequency {
+		return
+	}
+
+	for key, entry := range s.entries {
+		if now.After(entry.expiresAt) {
+			delete(s.entries, key)
+		}
+	}
+	s.lastSweep = now
+}
+
+// Idempotency replays the first response of a request carrying an
+// Idempotency-Key header for every retry with the same key, so a retried
+// create doesn't create twice. Requests without the header pass through.
+//
+// The key is reserved before the handler runs: a duplicate arriving while the
+// first request is in flight gets a 409, and reusing a key with another body
+// gets a 422.
+type Idempotency struct {
+	store        IdempotencyStore
+	ttl          time.Duration
+	maxBodyBytes int64
+}
+
+// NewIdempotency reads at most maxBodyBytes of a request to fingerprint it
+func NewIdempotency(store IdempotencyStore, ttl time.Duration, maxBodyBytes int64) *Idempotency {
+	return &Idempotency{store: store, ttl: ttl, maxBodyBytes: maxBodyBytes}
+}
+
+func (i *Idempotency) Handle(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(IdempotencyKeyHeader)
+		if key == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if len(key) > maxIdempotencyKeyLength {
+			utils.WriteAPIError(w, utils.BadRequest("Idempotency-Key is too long"))
+			return
+		}
+
+		body, err := readBody(w, r, i.maxBodyBytes)
+		if err != nil {
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				utils.WriteAPIError(w, utils.PayloadTooLarge(fmt.Sprintf("request body must not exceed %d bytes", maxBytesErr.Limit)))
+				return
+			}
+			utils.WriteAPIError(w, utils.BadRequest(err.Error()))
+			return
+		}
+
+		bodyHash := sha256.Sum256(body)
+		requestHash := hex.EncodeToString(bodyHash[:])
+
+		scoped := scopeIdempotencyKey(r, key)
+		entry, reserved := i.store.Reserve(scoped, requestHash, i.ttl)
+		if !reserved {
+			switch {
+			case entry.RequestHash != requestHash:
+				utils.WriteAPIError(w, utils.UnprocessableEntity("Idempotency-Key was already used for another request"))
+			case entry.Response == nil:
+				utils.WriteAPIError(w, utils.Conflict("a request with this Idempotency-Key is still in progress"))
+			default:
+				for name, values := range entry.Response.Header {
+					w.Header()[name] = values
+				}
+				w.Header().Set(IdempotentReplayedHeader, "true")
+				w.WriteHeader(entry.Response.Status)
+				w.Write(entry.Response.Body)
+			}
+			return
+		}
+
+		// A panic or a server error frees the key, a retry should get another
+		// chance
+		completed := false
+		defer func() {
+			if !completed {
+				i.store.Release(scoped)
+			}
+		}()
+
+		recorder := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(recorder, r)
+
+		if recorder.status < http.StatusInternalServerError {
+			i.store.Complete(scoped, &CachedResponse{
+				Status: recorder.status,
+				Header: w.Header().Clone(),
+				Body:   recorder.body.Bytes(),
+			}, i.ttl)
+			completed = true
+		}
+	})
+}
+
+// scopeIdempotencyKey ties a key to the route and the caller, so two clients
+// picking the same key don't see each other's responses. Callers are told
+// apart by their credentials, or by their IP on routes without any.
+func scopeIdempotencyKey(r *http.Request, key string) string {
+	caller := utils.GetTokenFromRequest(r)
+	if caller == "" {
+		caller = "ip:" + clientIP(r)
+	}
+
+	hashed := sha256.Sum256([]byte(caller))
+	return r.Method + " " + r.URL.Path + " " + hex.EncodeToString(hashed[:]) + " " + key
+}
+
+// readBody reads the request body and puts it back for the next handler
+func readBody(w http.ResponseWriter, r *http.Request, maxBytes int64) ([]byte, error) {
+	if r.Body == nil {
+		return nil, nil
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBytes))
+	if err != nil {
+		return nil, err
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	return body, nil
+}
+
+// responseRecorder passes the response through while keeping a copy
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
@@ -0,0 +1,119 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestIdempotency(t *testing.T) {
+	calls := 0
+	handler := NewIdempotency(NewMemoryIdempotencyStore(), time.Minute, 1<<20).Handle(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"id":1}`))
+	}))
+
+	send := func(handler http.Handler, key, body, remoteAddr string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/products", strings.NewReader(body))
+		req.RemoteAddr = remoteAddr
+		if key != "" {
+			req.Header.Set(IdempotencyKeyHeader, key)
+		}
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	t.Run("should replay the first response for a repeated key", func(t *testing.T) {
+		calls = 0
+		first := send(handler, "abc", `{"name":"chair"}`, "10.0.0.1:1234")
+		second := send(handler, "abc", `{"name":"chair"}`, "10.0.0.1:1234")
+
+		if calls != 1 {
+			t.Errorf("expected the handler to run once, ran %d times", calls)
+		}
+
+		if second.Code != http.StatusCreated || second.Body.String() != first.Body.String() {
+			t.Errorf("expected the cached response, got %d %q", second.Code, second.Body.String())
+		}
+
+		if second.Header().Get(IdempotentReplayedHeader) != "true" {
+			t.Error("expected the replay to be marked")
+		}
+	})
+
+	t.Run("should reject a reused key with another body", func(t *testing.T) {
+		send(handler, "reused", `{"name":"chair"}`, "10.0.0.1:1234")
+		rr := send(handler, "reused", `{"name":"table"}`, "10.0.0.1:1234")
+
+		if rr.Code != http.StatusUnprocessableEntity {
+			t.Errorf("expexted status code %d, got %d", http.StatusUnprocessableEntity, rr.Code)
+		}
+	})
+
+	t.Run("should keep anonymous clients apart", func(t *testing.T) {
+		calls = 0
+		send(handler, "shared", `{"name":"chair"}`, "10.0.0.1:1234")
+		rr := send(handler, "shared", `{"name":"chair"}`, "10.0.0.2:1234")
+
+		if calls != 2 || rr.Header().Get(IdempotentReplayedHeader) != "" {
+			t.Errorf("expected the second client to run its own request, ran %d times", calls)
+		}
+	})
+
+	t.Run("should run every request without a key", func(t *testing.T) {
+		calls = 0
+		send(handler, "", "", "10.0.0.1:1234")
+		send(handler, "", "", "10.0.0.1:1234")
+
+		if calls != 2 {
+			t.Errorf("expected the handler to run twice, ran %d times", calls)
+		}
+	})
+
+	t.Run("should answer a duplicate in flight with a conflict", func(t *testing.T) {
+		started := make(chan struct{})
+		release := make(chan struct{})
+		slow := NewIdempotency(NewMemoryIdempotencyStore(), time.Minute, 1<<20).Handle(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			close(started)
+			<-release
+			w.WriteHeader(http.StatusCreated)
+		}))
+
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			send(slow, "abc", "{}", "10.0.0.1:1234")
+		}()
+
+		<-started
+		rr := send(slow, "abc", "{}", "10.0.0.1:1234")
+		close(release)
+		wg.Wait()
+
+		if rr.Code != http.StatusConflict {
+			t.Errorf("expexted status code %d, got %d", http.StatusConflict, rr.Code)
+		}
+	})
+
+	t.Run("should not cache a server error", func(t *testing.T) {
+		failures := 0
+		failing := NewIdempotency(NewMemoryIdempotencyStore(), time.Minute, 1<<20).Handle(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			failures++
+			w.WriteHeader(http.StatusInternalServerError)
+		}))
+
+		send(failing, "abc", "{}", "10.0.0.1:1234")
+		rr := send(failing, "abc", "{}", "10.0.0.1:1234")
+
+		if failures != 2 || rr.Header().Get(IdempotentReplayedHeader) != "" {
+			t.Errorf("expected the retry to run again, ran %d times", failures)
+		}
+	})
+}
@@ -0,0 +1,109 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Jay1570/learning-go/utils"
+)
+
+// ErrInvalidRateLimit is returned for a limit or window that isn't positive
+var ErrInvalidRateLimit = errors.New("rate limit and window must be positive")
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// RateLimiter is an in-memory token-bucket limiter keyed by client IP
+type RateLimiter struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	capacity  float64
+	rate      float64 // tokens refilled per second
+	window    time.Duration
+	lastSweep time.Time
+}
+
+// NewRateLimiter allows up to limit requests per window for every client
+func NewRateLimiter(limit int, window time.Duration) (*RateLimiter, error) {
+	if limit <= 0 || window <= 0 {
+		return nil, fmt.Errorf("%w: got %d per %s", ErrInvalidRateLimit, limit, window)
+	}
+
+	return &RateLimiter{
+		buckets:   make(map[string]*bucket),
+		capacity:  float64(limit),
+		rate:      float64(limit) / window.Seconds(),
+		window:    window,
+		lastSweep: time.Now(),
+	}, nil
+}
+
+// Allow consumes a token for key, returning how long to wait when none is left
+func (rl *RateLimiter) Allow(key string) (bool, time.Duration) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	now := time.Now()
+	rl.sweep(now)
+
+	b, ok := rl.buckets[key]
+	if !ok {
+		b = &bucket{tokens: rl.capacity, last: now}
+		rl.buckets[key] = b
+	}
+
+	b.tokens = math.Min(rl.capacity, b.tokens+now.Sub(b.last).Seconds()*rl.rate)
+	b.last = now
+
+	if b.tokens < 1 {
+		wait := time.Duration((1 - b.tokens) / rl.rate * float64(time.Second))
+		return false, wait
+	}
+
+	b.tokens--
+	return true, 0
+}
+
+// sweep drops buckets that have been idle long enough to be full again
+func (rl *RateLimiter) sweep(now time.Time) {
+	if now.Sub(rl.lastSweep) < rl.window {
+		return
+	}
+
+	for key, b := range rl.buckets {
+		if now.Sub(b.last) >= rl.window {
+			delete(rl.buckets, key)
+		}
+	}
+	rl.lastSweep = now
+}
+
+func (rl *RateLimiter) Limit(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		allowed, wait := rl.Allow(clientIP(r))
+		if !allowed {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			utils.WriteError(w, http.StatusTooManyRequests, fmt.Errorf("too many requests"))
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
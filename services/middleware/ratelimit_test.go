@@ -0,0 +1,77 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRateLimiter(t *testing.T) {
+	t.Run("should reject a limit or window that isn't positive", func(t *testing.T) {
+		for _, tt := range []struct {
+			limit  int
+			window time.Duration
+		}{
+			{0, time.Minute},
+			{-1, time.Minute},
+			{5, 0},
+		} {
+			if _, err := NewRateLimiter(tt.limit, tt.window); !errors.Is(err, ErrInvalidRateLimit) {
+				t.Errorf("%d per %s: expected ErrInvalidRateLimit, got %v", tt.limit, tt.window, err)
+			}
+		}
+	})
+
+	t.Run("should refill the bucket over the window", func(t *testing.T) {
+		rl, err := NewRateLimiter(2, time.Minute)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rl.Allow("10.0.0.1")
+		rl.Allow("10.0.0.1")
+		if allowed, _ := rl.Allow("10.0.0.1"); allowed {
+			t.Fatal("expected the empty bucket to refuse")
+		}
+
+		// Half the window refills one of the two tokens
+		rl.buckets["10.0.0.1"].last = time.Now().Add(-30 * time.Second)
+		if allowed, _ := rl.Allow("10.0.0.1"); !allowed {
+			t.Error("expected a refilled token")
+		}
+		if allowed, _ := rl.Allow("10.0.0.1"); allowed {
+			t.Error("expected only one token to be refilled")
+		}
+	})
+
+	t.Run("should answer with 429 and Retry-After", func(t *testing.T) {
+		rl, err := NewRateLimiter(1, time.Minute)
+		if err != nil {
+			t.Fatal(err)
+		}
+		handler := rl.Limit(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+		send := func() *httptest.ResponseRecorder {
+			req := httptest.NewRequest(http.MethodPost, "/login", nil)
+			req.RemoteAddr = "10.0.0.1:1234"
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+			return rr
+		}
+
+		if rr := send(); rr.Code != http.StatusOK {
+			t.Fatalf("expexted status code %d, got %d", http.StatusOK, rr.Code)
+		}
+
+		rr := send()
+		if rr.Code != http.StatusTooManyRequests {
+			t.Fatalf("expexted status code %d, got %d", http.StatusTooManyRequests, rr.Code)
+		}
+		if retryAfter := rr.Header().Get("Retry-After"); retryAfter != "60" {
+			t.Errorf("expected Retry-After 60, got %q", retryAfter)
+		}
+	})
+}
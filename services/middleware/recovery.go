@@ -0,0 +1,28 @@
+package middleware
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/Jay1570/learning-go/utils"
+)
+
+func Recovery(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			if err := recover(); err != nil {
+				// http.ErrAbortHandler is the documented way to abort a response
+				if err == http.ErrAbortHandler {
+					panic(err)
+				}
+
+				slog.Error("panic", "error", err, "path", r.URL.Path, "stack", string(debug.Stack()))
+				utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("internal server error"))
+			}
+		}()
+
+		next.ServeHTTP(w, r)
+	})
+}
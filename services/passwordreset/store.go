@@ -0,0 +1,50 @@
+package passwordreset
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/Jay1570/learning-go/config"
+	"github.com/Jay1570/learning-go/db"
+	"github.com/Jay1570/learning-go/services/singleuse"
+	"github.com/Jay1570/learning-go/types"
+)
+
+type Store struct {
+	tokens *singleuse.Store
+}
+
+func NewStore(db *sql.DB) *Store {
+	expiration := time.Second * time.Duration(config.Envs.PasswordResetTokenExpirationInSeconds)
+	return &Store{tokens: singleuse.NewStore(db, "password_reset_tokens", expiration, types.ErrInvalidPasswordResetToken)}
+}
+
+func (s *Store) CreatePasswordResetToken(userID int) (string, error) {
+	return s.tokens.Create(userID)
+}
+
+// ResetPassword spends the token and sets the password of its owner in one
+// transaction: a failed update leaves the token usable. Each token can only
+// be used once.
+func (s *Store) ResetPassword(token, hashedPassword string) (int, error) {
+	var userID int
+	err := s.tokens.ConsumeWith(token, func(tx *sql.Tx, owner int) error {
+		updated, err := db.UpdateCount[types.User](tx, "users", types.User{Password: hashedPassword}, &db.QueryOptions{
+			Where:     "id = ?",
+			WhereArgs: []interface{}{owner},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to update password: %w", err)
+		}
+
+		if updated == 0 {
+			return types.ErrUserNotFound
+		}
+
+		userID = owner
+		return nil
+	})
+
+	return userID, err
+}
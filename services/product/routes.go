@@ -1,15 +1,31 @@
 package product
 
 import (
-	"fmt"
+	"database/sql"
+	"errors"
+	"math"
 	"net/http"
+	"strconv"
+	"time"
 
+	"github.com/Jay1570/learning-go/config"
+	"github.com/Jay1570/learning-go/db"
 	"github.com/Jay1570/learning-go/services/auth"
+	"github.com/Jay1570/learning-go/services/middleware"
 	"github.com/Jay1570/learning-go/types"
 	"github.com/Jay1570/learning-go/utils"
-	"github.com/go-playground/validator/v10"
 )
 
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+
+	defaultLowStockThreshold = 5
+)
+
+// sortableColumns is the allowlist for the sort param of the listing
+var sortableColumns = []string{"name", "price", "quantity", "createdAt"}
+
 type Handler struct {
 	store     types.ProductStore
 	userStore types.UserStore
@@ -23,14 +39,69 @@ func (h *Handler) RegisterRoutes(router *http.ServeMux) {
 	productRouter := http.NewServeMux()
 
 	productRouter.HandleFunc("GET /products", h.handleGetProducts)
-	productRouter.HandleFunc("POST /products", h.handleCreateProduct)
+	productRouter.HandleFunc("GET /products/{id}", h.handleGetProduct)
+
+	// Only admins may add to the catalogue; a product can then be changed by
+	// whoever created it or by any admin
+	adminOnly := auth.RequireRole(types.RoleAdmin)
+	idempotency := middleware.NewIdempotency(middleware.NewMemoryIdempotencyStore(),
+		time.Duration(config.Envs.IdempotencyKeyTTLInSeconds)*time.Second, config.Envs.MaxRequestBodyBytes)
+	productRouter.Handle("GET /products/low-stock", adminOnly(http.HandlerFunc(h.handleGetLowStockProducts)))
+	productRouter.Handle("GET /products/stats", adminOnly(http.HandlerFunc(h.handleGetProductStats)))
+	productRouter.Handle("POST /products", adminOnly(idempotency.Handle(http.HandlerFunc(h.handleCreateProduct))))
+	productRouter.HandleFunc("PUT /products/{id}", h.handleUpdateProduct)
+	productRouter.HandleFunc("DELETE /products/{id}", h.handleDeleteProduct)
 
 	router.Handle("/", auth.WithJWTAuth(productRouter, h.userStore))
 	// router.HandleFunc("/products", h.handleRegister)
 }
 
 func (h *Handler) handleGetProducts(w http.ResponseWriter, r *http.Request) {
-	products, err := h.store.GetProducts()
+	page, err := utils.QueryInt(r, "page", 1, 1, math.MaxInt32)
+	if err != nil {
+		utils.WriteAPIError(w, err)
+		return
+	}
+
+	limit, err := utils.QueryInt(r, "limit", defaultPageSize, 1, maxPageSize)
+	if err != nil {
+		utils.WriteAPIError(w, err)
+		return
+	}
+
+	filter, err := parseFilter(r)
+	if err != nil {
+		utils.WriteAPIError(w, err)
+		return
+	}
+
+	filter.Order, err = utils.ParseSort(r, sortableColumns)
+	if err != nil {
+		utils.WriteAPIError(w, err)
+		return
+	}
+
+	result, err := h.store.GetProductsPaginated(filter, limit, (page-1)*limit)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	response := map[string]any{
+		"status":   http.StatusOK,
+		"products": utils.Paginate(result, page, limit),
+	}
+	utils.WriteJSON(w, response["status"].(int), response)
+}
+
+func (h *Handler) handleGetLowStockProducts(w http.ResponseWriter, r *http.Request) {
+	threshold, err := utils.QueryInt(r, "threshold", defaultLowStockThreshold, 1, math.MaxInt32)
+	if err != nil {
+		utils.WriteAPIError(w, err)
+		return
+	}
+
+	products, err := h.store.GetLowStockProducts(threshold)
 	if err != nil {
 		utils.WriteError(w, http.StatusInternalServerError, err)
 		return
@@ -43,16 +114,59 @@ func (h *Handler) handleGetProducts(w http.ResponseWriter, r *http.Request) {
 	utils.WriteJSON(w, response["status"].(int), response)
 }
 
+func (h *Handler) handleGetProductStats(w http.ResponseWriter, r *http.Request) {
+	stats, err := h.store.GetProductStats()
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	response := map[string]any{
+		"status": http.StatusOK,
+		"stats":  stats,
+	}
+	utils.WriteJSON(w, response["status"].(int), response)
+}
+
+func (h *Handler) handleGetProduct(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		utils.WriteAPIError(w, utils.BadRequest("invalid product id"))
+		return
+	}
+
+	product, err := h.store.GetProductByID(id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			utils.WriteAPIError(w, utils.NotFound("product not found"))
+			return
+		}
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	response := map[string]any{
+		"status":  http.StatusOK,
+		"product": product,
+	}
+	utils.WriteJSON(w, response["status"].(int), response)
+}
+
 func (h *Handler) handleCreateProduct(w http.ResponseWriter, r *http.Request) {
 	var payload types.CreateProductPayload
 	if err := utils.ParseJSON(r, &payload); err != nil {
-		utils.WriteError(w, http.StatusBadRequest, err)
+		utils.WriteAPIError(w, err)
 		return
 	}
 
 	if err := utils.Validate.Struct(payload); err != nil {
-		errors := err.(validator.ValidationErrors)
-		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid payload: %v", errors))
+		utils.WriteValidationError(w, err)
+		return
+	}
+
+	userID, ok := auth.GetUserIDFromContext(r.Context())
+	if !ok {
+		utils.WriteAPIError(w, utils.Unauthorized("unauthorized"))
 		return
 	}
 
@@ -62,8 +176,14 @@ func (h *Handler) handleCreateProduct(w http.ResponseWriter, r *http.Request) {
 		Image:       payload.Image,
 		Price:       payload.Price,
 		Quantity:    payload.Quantity,
+		CreatedBy:   &userID,
+		CategoryID:  payload.CategoryID,
 	})
 	if err != nil {
+		if errors.Is(err, types.ErrDuplicateProduct) {
+			utils.WriteAPIError(w, utils.Conflict(err.Error()))
+			return
+		}
 		utils.WriteError(w, http.StatusInternalServerError, err)
 		return
 	}
@@ -74,3 +194,142 @@ func (h *Handler) handleCreateProduct(w http.ResponseWriter, r *http.Request) {
 	}
 	utils.WriteJSON(w, response["status"].(int), response)
 }
+
+func (h *Handler) handleUpdateProduct(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		utils.WriteAPIError(w, utils.BadRequest("invalid product id"))
+		return
+	}
+
+	if !h.authorizeMutation(w, r, id) {
+		return
+	}
+
+	var payload types.UpdateProductPayload
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		utils.WriteAPIError(w, err)
+		return
+	}
+
+	if err := utils.Validate.Struct(payload); err != nil {
+		utils.WriteValidationError(w, err)
+		return
+	}
+
+	product, err := h.store.UpdateProduct(id, payload)
+	if err != nil {
+		switch {
+		case errors.Is(err, db.ErrNoFieldsToUpdate):
+			utils.WriteError(w, http.StatusBadRequest, err)
+		case errors.Is(err, sql.ErrNoRows):
+			utils.WriteAPIError(w, utils.NotFound("product not found"))
+		case errors.Is(err, types.ErrDuplicateProduct):
+			utils.WriteAPIError(w, utils.Conflict(err.Error()))
+		default:
+			utils.WriteError(w, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	response := map[string]any{
+		"status":  http.StatusOK,
+		"product": product,
+	}
+	utils.WriteJSON(w, response["status"].(int), response)
+}
+
+func (h *Handler) handleDeleteProduct(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		utils.WriteAPIError(w, utils.BadRequest("invalid product id"))
+		return
+	}
+
+	if !h.authorizeMutation(w, r, id) {
+		return
+	}
+
+	if err := h.store.DeleteProduct(id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			utils.WriteAPIError(w, utils.NotFound("product not found"))
+			return
+		}
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	response := map[string]any{
+		"status":  http.StatusOK,
+		"message": "Product successfully deleted",
+	}
+	utils.WriteJSON(w, response["status"].(int), response)
+}
+
+// authorizeMutation lets the owner of the product or an admin through and
+// writes the error response otherwise
+func (h *Handler) authorizeMutation(w http.ResponseWriter, r *http.Request, id int) bool {
+	userID, ok := auth.GetUserIDFromContext(r.Context())
+	if !ok {
+		utils.WriteAPIError(w, utils.Unauthorized("unauthorized"))
+		return false
+	}
+
+	if auth.GetRoleFromContext(r.Context()) == types.RoleAdmin {
+		return true
+	}
+
+	product, err := h.store.GetProductByID(id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			utils.WriteAPIError(w, utils.NotFound("product not found"))
+			return false
+		}
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return false
+	}
+
+	if product.CreatedBy == nil || *product.CreatedBy != userID {
+		utils.WriteAPIError(w, utils.Forbidden("only the owner or an admin can change this product"))
+		return false
+	}
+
+	return true
+}
+
+// parseFilter reads the q, minPrice, maxPrice and inStock query params
+func parseFilter(r *http.Request) (types.ProductFilter, error) {
+	filter := types.ProductFilter{Query: r.URL.Query().Get("q")}
+
+	var err error
+	if filter.MinPrice, err = queryPrice(r, "minPrice"); err != nil {
+		return filter, err
+	}
+	if filter.MaxPrice, err = queryPrice(r, "maxPrice"); err != nil {
+		return filter, err
+	}
+
+	if filter.MinPrice != nil && filter.MaxPrice != nil && *filter.MinPrice > *filter.MaxPrice {
+		return filter, utils.BadRequest("minPrice must not exceed maxPrice")
+	}
+
+	if filter.InStock, err = utils.QueryBool(r, "inStock", false); err != nil {
+		return filter, err
+	}
+
+	return filter, nil
+}
+
+// queryPrice reads an optional price bound, nil when absent
+func queryPrice(r *http.Request, key string) (*float64, error) {
+	if r.URL.Query().Get(key) == "" {
+		return nil, nil
+	}
+
+	price, err := utils.QueryFloat(r, key, 0, 0, math.MaxFloat64)
+	if err != nil {
+		return nil, err
+	}
+
+	return &price, nil
+}
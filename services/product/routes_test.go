@@ -0,0 +1,356 @@
+package product
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Jay1570/learning-go/services/auth"
+	"github.com/Jay1570/learning-go/testutil"
+	"github.com/Jay1570/learning-go/types"
+)
+
+func TestProductService(t *testing.T) {
+	productStore := testutil.NewProductStore(
+		types.Product{Name: "chair", Price: 1000, Quantity: 3},
+		types.Product{Name: "table", Price: 5000, Quantity: 0},
+	)
+	handler := NewHandler(productStore, testutil.NewUserStore())
+
+	t.Run("should get a product by id", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodGet, "/products/1", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rr := httptest.NewRecorder()
+		router := http.NewServeMux()
+
+		router.HandleFunc("GET /products/{id}", handler.handleGetProduct)
+		router.ServeHTTP(rr, req)
+
+		if rr.Code != http.StatusOK {
+			t.Errorf("expexted status code %d, got %d", http.StatusOK, rr.Code)
+		}
+	})
+
+	t.Run("should fail if the product doesn't exist", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodGet, "/products/42", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rr := httptest.NewRecorder()
+		router := http.NewServeMux()
+
+		router.HandleFunc("GET /products/{id}", handler.handleGetProduct)
+		router.ServeHTTP(rr, req)
+
+		if rr.Code != http.StatusNotFound {
+			t.Errorf("expexted status code %d, got %d", http.StatusNotFound, rr.Code)
+		}
+	})
+
+	t.Run("should paginate the product listing", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodGet, "/products?page=1&limit=10", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rr := httptest.NewRecorder()
+		router := http.NewServeMux()
+
+		router.HandleFunc("GET /products", handler.handleGetProducts)
+		router.ServeHTTP(rr, req)
+
+		if rr.Code != http.StatusOK {
+			t.Errorf("expexted status code %d, got %d", http.StatusOK, rr.Code)
+		}
+	})
+	t.Run("should filter the product listing", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodGet, "/products?q=CHA&maxPrice=20&inStock=true", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rr := httptest.NewRecorder()
+		router := http.NewServeMux()
+
+		router.HandleFunc("GET /products", handler.handleGetProducts)
+		router.ServeHTTP(rr, req)
+
+		if rr.Code != http.StatusOK {
+			t.Errorf("expexted status code %d, got %d", http.StatusOK, rr.Code)
+		}
+
+		var body struct {
+			Products struct {
+				Data       []types.Product `json:"data"`
+				TotalCount int             `json:"totalCount"`
+			} `json:"products"`
+		}
+		if err := json.Unmarshal(rr.Body.Bytes(), &body); err != nil {
+			t.Fatal(err)
+		}
+		if body.Products.TotalCount != 1 || len(body.Products.Data) != 1 || body.Products.Data[0].Name != "chair" {
+			t.Errorf("expected only the chair, got %+v", body.Products)
+		}
+	})
+
+	t.Run("should reject a malformed price filter", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodGet, "/products?minPrice=cheap", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rr := httptest.NewRecorder()
+		router := http.NewServeMux()
+
+		router.HandleFunc("GET /products", handler.handleGetProducts)
+		router.ServeHTTP(rr, req)
+
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("expexted status code %d, got %d", http.StatusBadRequest, rr.Code)
+		}
+	})
+	t.Run("should sort the product listing", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodGet, "/products?sort=-price", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rr := httptest.NewRecorder()
+		router := http.NewServeMux()
+
+		router.HandleFunc("GET /products", handler.handleGetProducts)
+		router.ServeHTTP(rr, req)
+
+		if rr.Code != http.StatusOK {
+			t.Errorf("expexted status code %d, got %d", http.StatusOK, rr.Code)
+		}
+
+		var body struct {
+			Products struct {
+				Data []types.Product `json:"data"`
+			} `json:"products"`
+		}
+		if err := json.Unmarshal(rr.Body.Bytes(), &body); err != nil {
+			t.Fatal(err)
+		}
+		if len(body.Products.Data) != 2 || body.Products.Data[0].Name != "table" {
+			t.Errorf("expected the table first, got %+v", body.Products.Data)
+		}
+	})
+
+	t.Run("should reject a column outside the sort allowlist", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodGet, "/products?sort=password", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rr := httptest.NewRecorder()
+		router := http.NewServeMux()
+
+		router.HandleFunc("GET /products", handler.handleGetProducts)
+		router.ServeHTTP(rr, req)
+
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("expexted status code %d, got %d", http.StatusBadRequest, rr.Code)
+		}
+	})
+	t.Run("should reject a malformed page", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodGet, "/products?page=abc", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rr := httptest.NewRecorder()
+		router := http.NewServeMux()
+
+		router.HandleFunc("GET /products", handler.handleGetProducts)
+		router.ServeHTTP(rr, req)
+
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("expexted status code %d, got %d", http.StatusBadRequest, rr.Code)
+		}
+	})
+	t.Run("should list the products running low", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodGet, "/products/low-stock?threshold=1", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rr := httptest.NewRecorder()
+		router := http.NewServeMux()
+
+		router.HandleFunc("GET /products/low-stock", handler.handleGetLowStockProducts)
+		router.ServeHTTP(rr, req)
+
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expexted status code %d, got %d", http.StatusOK, rr.Code)
+		}
+
+		var body struct {
+			Products []types.Product `json:"products"`
+		}
+		if err := json.NewDecoder(rr.Body).Decode(&body); err != nil {
+			t.Fatal(err)
+		}
+
+		if len(body.Products) != 1 || body.Products[0].Name != "table" {
+			t.Errorf("expected only the table, got %+v", body.Products)
+		}
+	})
+
+	t.Run("should summarize the catalogue", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodGet, "/products/stats", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rr := httptest.NewRecorder()
+		router := http.NewServeMux()
+
+		router.HandleFunc("GET /products/stats", handler.handleGetProductStats)
+		router.ServeHTTP(rr, req)
+
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expexted status code %d, got %d", http.StatusOK, rr.Code)
+		}
+
+		var body struct {
+			Stats types.ProductStats `json:"stats"`
+		}
+		if err := json.NewDecoder(rr.Body).Decode(&body); err != nil {
+			t.Fatal(err)
+		}
+
+		expected := types.ProductStats{Count: 2, InventoryValue: 3000, AveragePrice: 30, OutOfStock: 1}
+		if body.Stats != expected {
+			t.Errorf("expected %+v, got %+v", expected, body.Stats)
+		}
+	})
+
+	t.Run("should reject a negative or zero price and a negative quantity", func(t *testing.T) {
+		for _, tt := range []struct {
+			payload string
+			field   string
+		}{
+			{`{"name": "stool", "price": -5, "quantity": 1}`, "price"},
+			{`{"name": "stool", "price": 0, "quantity": 1}`, "price"},
+			{`{"name": "stool", "price": 5, "quantity": -1}`, "quantity"},
+		} {
+			req, err := http.NewRequest(http.MethodPost, "/products", strings.NewReader(tt.payload))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rr := httptest.NewRecorder()
+			router := http.NewServeMux()
+
+			router.HandleFunc("POST /products", handler.handleCreateProduct)
+			router.ServeHTTP(rr, req)
+
+			if rr.Code != http.StatusBadRequest {
+				t.Errorf("%s: expexted status code %d, got %d", tt.payload, http.StatusBadRequest, rr.Code)
+			}
+
+			var body struct {
+				Error struct {
+					Details map[string]string `json:"details"`
+				} `json:"error"`
+			}
+			if err := json.Unmarshal(rr.Body.Bytes(), &body); err != nil {
+				t.Fatal(err)
+			}
+			if _, ok := body.Error.Details[tt.field]; !ok {
+				t.Errorf("%s: expected a field error for %s, got %v", tt.payload, tt.field, body.Error.Details)
+			}
+		}
+	})
+
+	t.Run("should accept a product that is out of stock", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodPost, "/products", strings.NewReader(`{"name": "stool", "price": 5, "quantity": 0}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req = req.WithContext(context.WithValue(req.Context(), auth.UserIDKey, 1))
+
+		rr := httptest.NewRecorder()
+		router := http.NewServeMux()
+
+		router.HandleFunc("POST /products", handler.handleCreateProduct)
+		router.ServeHTTP(rr, req)
+
+		if rr.Code != http.StatusCreated {
+			t.Errorf("expexted status code %d, got %d", http.StatusCreated, rr.Code)
+		}
+	})
+
+	t.Run("should reject a name that is already taken", func(t *testing.T) {
+		for _, tt := range []struct {
+			method string
+			path   string
+			body   string
+		}{
+			{http.MethodPost, "/products", `{"name": "chair", "price": 5, "quantity": 1}`},
+			{http.MethodPut, "/products/2", `{"name": "chair"}`},
+		} {
+			req, err := http.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			ctx := context.WithValue(req.Context(), auth.UserIDKey, 1)
+			req = req.WithContext(context.WithValue(ctx, auth.RoleKey, types.RoleAdmin))
+
+			rr := httptest.NewRecorder()
+			router := http.NewServeMux()
+
+			router.HandleFunc("POST /products", handler.handleCreateProduct)
+			router.HandleFunc("PUT /products/{id}", handler.handleUpdateProduct)
+			router.ServeHTTP(rr, req)
+
+			if rr.Code != http.StatusConflict {
+				t.Errorf("%s %s: expexted status code %d, got %d", tt.method, tt.path, http.StatusConflict, rr.Code)
+			}
+		}
+	})
+
+	t.Run("should only let the owner or an admin change a product", func(t *testing.T) {
+		ownerID := 7
+		if err := productStore.CreateProduct(types.Product{ID: 10, Name: "lamp", Price: 500, Quantity: 1, CreatedBy: &ownerID}); err != nil {
+			t.Fatal(err)
+		}
+
+		for _, tt := range []struct {
+			name     string
+			userID   int
+			role     string
+			expected int
+		}{
+			{"owner", ownerID, types.RoleUser, http.StatusOK},
+			{"admin", 1, types.RoleAdmin, http.StatusOK},
+			{"someone else", 8, types.RoleUser, http.StatusForbidden},
+		} {
+			req, err := http.NewRequest(http.MethodPut, "/products/10", strings.NewReader(`{"price": 6}`))
+			if err != nil {
+				t.Fatal(err)
+			}
+			ctx := context.WithValue(req.Context(), auth.UserIDKey, tt.userID)
+			req = req.WithContext(context.WithValue(ctx, auth.RoleKey, tt.role))
+
+			rr := httptest.NewRecorder()
+			router := http.NewServeMux()
+
+			router.HandleFunc("PUT /products/{id}", handler.handleUpdateProduct)
+			router.ServeHTTP(rr, req)
+
+			if rr.Code != tt.expected {
+				t.Errorf("%s: expexted status code %d, got %d", tt.name, tt.expected, rr.Code)
+			}
+		}
+	})
+}
@@ -2,19 +2,36 @@ package product
 
 import (
 	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
 
 	"github.com/Jay1570/learning-go/db"
 	"github.com/Jay1570/learning-go/types"
+	"github.com/go-sql-driver/mysql"
 )
 
+// mysqlErrDuplicateEntry is raised when a write hits the unique key on
+// products.name
+const mysqlErrDuplicateEntry = 1062
+
 type Store struct {
-	db *sql.DB
+	db    *sql.DB
+	cache *db.ReadCache
 }
 
 func NewStore(db *sql.DB) *Store {
 	return &Store{db: db}
 }
 
+// WithCache serves GetProductByID through a read-through cache; a store
+// without one always reads the database
+func (s *Store) WithCache(cache *db.ReadCache) *Store {
+	s.cache = cache
+	return s
+}
+
 func (s *Store) GetProducts() ([]types.Product, error) {
 	products, err := db.FindAll[types.Product](s.db, "products", &db.QueryOptions{})
 	if err != nil {
@@ -24,7 +41,270 @@ func (s *Store) GetProducts() ([]types.Product, error) {
 	return products, nil
 }
 
+func (s *Store) GetProductsPaginated(filter types.ProductFilter, limit, offset int) (*db.CountResult[types.Product], error) {
+	result, err := db.FindAllAndCount[types.Product](s.db, "products", &db.QueryOptions{
+		Conditions: filterConditions(filter),
+		Order:      append(slices.Clone(filter.Order), db.Asc("id")), // id keeps pages stable
+		Limit:      limit,
+		Offset:     offset,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// GetProductsWithCategory lists the products with the name of their category
+// in a single query. It is the reference for loading related data: join with
+// the builder and scan into a struct embedding the base type.
+func (s *Store) GetProductsWithCategory() ([]types.ProductWithCategory, error) {
+	builder := db.NewJoinBuilder("products").
+		Alias("p").
+		Join(db.NewLeftJoin("categories", "c.id = p.categoryId").As("c")).
+		Select("p.*, c.name AS categoryName").
+		Order(db.Asc("p.id"))
+
+	products, err := db.Execute[types.ProductWithCategory](s.db, builder)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get products with category: %w", err)
+	}
+
+	return products, nil
+}
+
+// GetProductsByPriceRange lists the products priced from min to max, both
+// included. A zero max leaves the range open at the top; a negative min or a
+// min above max is types.ErrInvalidPriceRange.
+func (s *Store) GetProductsByPriceRange(min, max float64) ([]types.Product, error) {
+	if min < 0 {
+		return nil, types.ErrInvalidPriceRange
+	}
+
+	options := &db.QueryOptions{
+		Where:     "price BETWEEN ? AND ?",
+		WhereArgs: []interface{}{min, max},
+		Order:     []db.OrderByClause{db.Asc("price"), db.Asc("id")},
+	}
+
+	if max == 0 {
+		options.Where = "price >= ?"
+		options.WhereArgs = []interface{}{min}
+	} else if min > max {
+		return nil, types.ErrInvalidPriceRange
+	}
+
+	products, err := db.FindAll[types.Product](s.db, "products", options)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get products by price range: %w", err)
+	}
+
+	return products, nil
+}
+
+// GetLowStockProducts lists the products with fewer than threshold units
+// left, the scarcest first
+func (s *Store) GetLowStockProducts(threshold int) ([]types.Product, error) {
+	products, err := db.FindAll[types.Product](s.db, "products", &db.QueryOptions{
+		Where:     "quantity < ?",
+		WhereArgs: []interface{}{threshold},
+		Order:     []db.OrderByClause{db.Asc("quantity"), db.Asc("id")},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get low stock products: %w", err)
+	}
+
+	return products, nil
+}
+
+// GetProductStats computes the dashboard totals over the whole catalogue
+func (s *Store) GetProductStats() (*types.ProductStats, error) {
+	var stats types.ProductStats
+	var err error
+
+	stats.Count, err = db.Count[types.Product](s.db, "products", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	stats.AveragePrice, err = db.Avg(s.db, "products", "price", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	stats.OutOfStock, err = db.Count[types.Product](s.db, "products", &db.QueryOptions{Where: "quantity = 0"})
+	if err != nil {
+		return nil, err
+	}
+
+	// The aggregate helpers take a single column, the product of two needs
+	// raw SQL
+	value, err := db.RawOne[struct {
+		Value types.Money `db:"value"`
+	}](s.db, "SELECT SUM(price * quantity) AS value FROM products")
+	if err != nil {
+		return nil, fmt.Errorf("failed to sum the inventory value: %w", err)
+	}
+	stats.InventoryValue = value.Value
+
+	return &stats, nil
+}
+
+// filterConditions translates a filter into parameterized where clauses
+func filterConditions(filter types.ProductFilter) []db.WhereClause {
+	var conditions []db.WhereClause
+
+	if q := strings.TrimSpace(filter.Query); q != "" {
+		pattern := "%" + likeEscaper.Replace(q) + "%"
+		conditions = append(conditions, db.WhereClause{
+			Expr: "name LIKE ? OR description LIKE ?",
+			Args: []interface{}{pattern, pattern},
+		})
+	}
+	if filter.MinPrice != nil {
+		conditions = append(conditions, db.WhereClause{Expr: "price >= ?", Args: []interface{}{*filter.MinPrice}})
+	}
+	if filter.MaxPrice != nil {
+		conditions = append(conditions, db.WhereClause{Expr: "price <= ?", Args: []interface{}{*filter.MaxPrice}})
+	}
+	if filter.InStock {
+		conditions = append(conditions, db.WhereClause{Expr: "quantity > 0"})
+	}
+
+	return conditions
+}
+
+// likeEscaper escapes LIKE wildcards so a search term matches literally
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+func (s *Store) GetProductByID(id int) (*types.Product, error) {
+	product, err := db.CachedFindByPK[types.Product](s.cache, s.db, "products", id)
+	if err != nil {
+		return nil, err
+	}
+
+	return product, nil
+}
+
 func (s *Store) CreateProduct(product types.Product) error {
 	_, err := db.InsertOne[types.Product](s.db, "products", product)
+	return duplicateProduct(err)
+}
+
+// UpdateProduct changes the fields set in payload; a field set to its zero
+// value, such as an empty description, is written too
+func (s *Store) UpdateProduct(id int, payload types.UpdateProductPayload) (*types.Product, error) {
+	_, err := db.UpdatePatch[types.Product](s.db, "products", payload, &db.QueryOptions{
+		Where:     "id = ?",
+		WhereArgs: []interface{}{id},
+	})
+	if err != nil {
+		return nil, duplicateProduct(err)
+	}
+
+	// MySQL counts changed rather than matched rows, so a missing product
+	// shows up on the re-read
+	return s.GetProductByID(id)
+}
+
+// duplicateProduct reports a write hitting the unique name as
+// types.ErrDuplicateProduct
+func duplicateProduct(err error) error {
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) && mysqlErr.Number == mysqlErrDuplicateEntry {
+		return types.ErrDuplicateProduct
+	}
+
 	return err
 }
+
+// AdjustStock runs the package level AdjustStock on the store's database
+func (s *Store) AdjustStock(productID, delta int) error {
+	return AdjustStock(s.db, productID, delta)
+}
+
+// AdjustStock adds delta (negative to take stock out) to the quantity of a
+// product in a transaction. The row is locked first so concurrent orders queue
+// up, and a decrement that would take the quantity below zero returns
+// types.ErrInsufficientStock and changes nothing.
+func AdjustStock(conn *sql.DB, productID, delta int) error {
+	// The quantity is changed with raw SQL, bypassing the invalidation of the
+	// write helpers
+	defer db.InvalidateTable("products")
+
+	return db.WithTransaction(conn, func(tx *sql.Tx) error {
+		current, err := db.FindOne[types.Product](tx, "products", &db.QueryOptions{
+			Where:     "id = ?",
+			WhereArgs: []interface{}{productID},
+			LockMode:  db.ForUpdate,
+		})
+		if err != nil {
+			return err
+		}
+
+		// quantity is INT UNSIGNED, so the check cannot go in the UPDATE:
+		// MySQL fails with an out of range error instead of matching no rows.
+		// The row lock keeps the quantity read above current until commit.
+		if current.Quantity+delta < 0 {
+			return types.ErrInsufficientStock
+		}
+
+		if _, err := tx.Exec("UPDATE products SET quantity = quantity + ? WHERE id = ?", delta, productID); err != nil {
+			return fmt.Errorf("failed to adjust stock: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// UpsertProducts imports a catalogue in one transaction. Products are matched
+// by their unique name; an existing one gets the price, quantity and
+// description of the import. A name appearing twice in the import rolls the
+// whole import back with types.ErrDuplicateProduct.
+func (s *Store) UpsertProducts(products []types.Product) (inserted, updated int, err error) {
+	err = db.WithTransaction(s.db, func(tx *sql.Tx) error {
+		inserted, updated = 0, 0
+		seen := make(map[string]bool, len(products))
+
+		for _, product := range products {
+			if seen[product.Name] {
+				return fmt.Errorf("%w: %q appears twice", types.ErrDuplicateProduct, product.Name)
+			}
+			seen[product.Name] = true
+
+			created, err := db.UpsertOne[types.Product](tx, "products", product, []string{"price", "quantity", "description"})
+			if err != nil {
+				return err
+			}
+
+			if created {
+				inserted++
+			} else {
+				updated++
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to upsert products: %w", err)
+	}
+
+	return inserted, updated, nil
+}
+
+func (s *Store) DeleteProduct(id int) error {
+	products, err := db.DeleteData[types.Product](s.db, "products", &db.QueryOptions{
+		Where:     "id = ?",
+		WhereArgs: []interface{}{id},
+	})
+	if err != nil {
+		return err
+	}
+
+	if len(products) == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
@@ -0,0 +1,203 @@
+package product
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"testing"
+
+	"github.com/Jay1570/learning-go/db"
+	"github.com/Jay1570/learning-go/testutil/fakedb"
+	"github.com/Jay1570/learning-go/types"
+	"github.com/go-sql-driver/mysql"
+)
+
+func TestStore(t *testing.T) {
+	t.Run("should count inserted and updated products of an import", func(t *testing.T) {
+		conn, fake := fakedb.New(t, nil)
+		// MySQL reports 1 for an insert, 2 for an update and 0 for an
+		// unchanged row
+		fake.RowsAffected = []int64{1, 2, 0}
+
+		inserted, updated, err := NewStore(conn).UpsertProducts([]types.Product{
+			{Name: "chair", Price: 1000},
+			{Name: "table", Price: 5000},
+			{Name: "lamp", Price: 500},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if inserted != 1 || updated != 2 {
+			t.Errorf("expected 1 inserted and 2 updated, got %d and %d", inserted, updated)
+		}
+		if fake.Commits != 1 {
+			t.Errorf("expected the import to commit, got %d commits", fake.Commits)
+		}
+	})
+
+	t.Run("should roll an import with a repeated name back", func(t *testing.T) {
+		conn, fake := fakedb.New(t, nil)
+
+		_, _, err := NewStore(conn).UpsertProducts([]types.Product{
+			{Name: "chair", Price: 1000},
+			{Name: "chair", Price: 2000},
+		})
+		if !errors.Is(err, types.ErrDuplicateProduct) {
+			t.Errorf("expected ErrDuplicateProduct, got %v", err)
+		}
+
+		if fake.Rollbacks != 1 || fake.Commits != 0 {
+			t.Errorf("expected a rollback, got %d rollbacks and %d commits", fake.Rollbacks, fake.Commits)
+		}
+	})
+
+	t.Run("should report a taken name as a duplicate", func(t *testing.T) {
+		conn, fake := fakedb.New(t, nil)
+		fake.Err = &mysql.MySQLError{Number: mysqlErrDuplicateEntry, Message: "Duplicate entry 'chair'"}
+
+		err := NewStore(conn).CreateProduct(types.Product{Name: "chair", Price: 1000})
+		if !errors.Is(err, types.ErrDuplicateProduct) {
+			t.Errorf("expected ErrDuplicateProduct, got %v", err)
+		}
+	})
+
+	t.Run("should update the stock without an unsigned arithmetic guard", func(t *testing.T) {
+		conn, fake := fakedb.New(t, []string{"id", "quantity"}, []driver.Value{int64(1), int64(3)})
+
+		if err := AdjustStock(conn, 1, -2); err != nil {
+			t.Fatal(err)
+		}
+
+		// A guard like "quantity + ? >= 0" overflows the unsigned column in
+		// MySQL instead of matching no rows
+		expected := "UPDATE products SET quantity = quantity + ? WHERE id = ?"
+		if len(fake.Queries) != 2 || fake.Queries[1] != expected {
+			t.Fatalf("expected %q after the locking read, got %v", expected, fake.Queries)
+		}
+		if len(fake.Args[1]) != 2 || fake.Args[1][0] != int64(-2) || fake.Args[1][1] != int64(1) {
+			t.Errorf("unexpected args %v", fake.Args[1])
+		}
+	})
+
+	t.Run("should refuse a decrement that would oversell", func(t *testing.T) {
+		conn, fake := fakedb.New(t, []string{"id", "quantity"}, []driver.Value{int64(1), int64(1)})
+
+		if err := AdjustStock(conn, 1, -2); !errors.Is(err, types.ErrInsufficientStock) {
+			t.Errorf("expected ErrInsufficientStock, got %v", err)
+		}
+		if len(fake.Queries) != 1 {
+			t.Errorf("expected no update, got %v", fake.Queries)
+		}
+		if fake.Rollbacks != 1 {
+			t.Errorf("expected a rollback, got %d", fake.Rollbacks)
+		}
+	})
+
+	t.Run("should accept an increment even when no row is reported changed", func(t *testing.T) {
+		conn, fake := fakedb.New(t, []string{"id", "quantity"}, []driver.Value{int64(1), int64(1)})
+		fake.RowsAffected = []int64{0}
+
+		if err := AdjustStock(conn, 1, 2); err != nil {
+			t.Errorf("expected no error, got %v", err)
+		}
+	})
+
+	t.Run("should report a missing product", func(t *testing.T) {
+		conn, fake := fakedb.New(t, []string{"id", "quantity"})
+
+		if err := AdjustStock(conn, 42, 1); !errors.Is(err, sql.ErrNoRows) {
+			t.Errorf("expected sql.ErrNoRows, got %v", err)
+		}
+		if len(fake.Queries) != 1 {
+			t.Errorf("expected no update, got %v", fake.Queries)
+		}
+	})
+
+	t.Run("should list the products in a price range", func(t *testing.T) {
+		conn, fake := fakedb.New(t, []string{"id", "price"}, []driver.Value{int64(1), int64(1000)})
+
+		products, err := NewStore(conn).GetProductsByPriceRange(500, 2000)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(products) != 1 {
+			t.Errorf("expected 1 product, got %+v", products)
+		}
+
+		expected := "SELECT * FROM products WHERE price BETWEEN ? AND ? ORDER BY price ASC, id ASC"
+		if fake.Queries[0] != expected {
+			t.Errorf("expected %q, got %q", expected, fake.Queries[0])
+		}
+	})
+
+	t.Run("should leave the price range open without a max", func(t *testing.T) {
+		conn, fake := fakedb.New(t, []string{"id", "price"})
+
+		if _, err := NewStore(conn).GetProductsByPriceRange(500, 0); err != nil {
+			t.Fatal(err)
+		}
+
+		expected := "SELECT * FROM products WHERE price >= ? ORDER BY price ASC, id ASC"
+		if fake.Queries[0] != expected {
+			t.Errorf("expected %q, got %q", expected, fake.Queries[0])
+		}
+	})
+
+	t.Run("should reject an invalid price range", func(t *testing.T) {
+		for _, tt := range []struct{ min, max float64 }{{2000, 500}, {-1, 500}, {-1, 0}} {
+			conn, fake := fakedb.New(t, []string{"id", "price"})
+
+			if _, err := NewStore(conn).GetProductsByPriceRange(tt.min, tt.max); !errors.Is(err, types.ErrInvalidPriceRange) {
+				t.Errorf("%g to %g: expected ErrInvalidPriceRange, got %v", tt.min, tt.max, err)
+			}
+			if len(fake.Queries) != 0 {
+				t.Errorf("%g to %g: expected no query, got %v", tt.min, tt.max, fake.Queries)
+			}
+		}
+	})
+
+	t.Run("should not write the id tiebreaker into the caller's order", func(t *testing.T) {
+		// The one row serves as both the count and the page
+		conn, _ := fakedb.New(t, []string{"id"}, []driver.Value{int64(1)})
+
+		// Spare capacity would let append write into the caller's array
+		order := make([]db.OrderByClause, 1, 2)
+		order[0] = db.Desc("price")
+
+		if _, err := NewStore(conn).GetProductsPaginated(types.ProductFilter{Order: order}, 10, 0); err != nil {
+			t.Fatal(err)
+		}
+
+		if spare := order[:2][1]; spare != (db.OrderByClause{}) {
+			t.Errorf("expected the caller's array to be untouched, got %+v", spare)
+		}
+	})
+
+	t.Run("should join the category name of every product", func(t *testing.T) {
+		conn, fake := fakedb.New(t, []string{"id", "name", "categoryName"},
+			[]driver.Value{int64(1), "chair", "furniture"},
+			[]driver.Value{int64(2), "gift card", nil},
+		)
+
+		products, err := NewStore(conn).GetProductsWithCategory()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected := "SELECT p.*, c.name AS categoryName FROM products p LEFT JOIN categories c ON c.id = p.categoryId ORDER BY p.id ASC"
+		if fake.Queries[0] != expected {
+			t.Errorf("expected %q, got %q", expected, fake.Queries[0])
+		}
+
+		if len(products) != 2 {
+			t.Fatalf("expected 2 products, got %+v", products)
+		}
+		if products[0].Name != "chair" || products[0].CategoryName == nil || *products[0].CategoryName != "furniture" {
+			t.Errorf("expected the chair in furniture, got %+v", products[0])
+		}
+		if products[1].CategoryName != nil {
+			t.Errorf("expected no category for the gift card, got %q", *products[1].CategoryName)
+		}
+	})
+}
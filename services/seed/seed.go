@@ -0,0 +1,125 @@
+// Package seed fills a development database with sample users and products.
+package seed
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/Jay1570/learning-go/config"
+	"github.com/Jay1570/learning-go/db"
+	"github.com/Jay1570/learning-go/services/auth"
+	"github.com/Jay1570/learning-go/types"
+	"github.com/Jay1570/learning-go/utils"
+)
+
+// ErrSeedingNotAllowed is returned when seeding is attempted outside the
+// environments in seedableEnvironments
+var ErrSeedingNotAllowed = errors.New("refusing to seed: APP_ENV must be development or test")
+
+// seedableEnvironments are the values of APP_ENV that allow seeding. The
+// sample admin has a well-known password, so an unset APP_ENV is refused too.
+var seedableEnvironments = []string{"development", "test"}
+
+// User is a sample account; Password is the plain text password
+type User struct {
+	FirstName string
+	LastName  string
+	Email     string
+	Password  string
+	Role      string
+}
+
+// Data is the set of records Seed inserts
+type Data struct {
+	Users    []User
+	Products []types.Product
+}
+
+// userRow is the insert payload of a seeded user. Unlike types.User it sets
+// the role and marks the email verified.
+type userRow struct {
+	FirstName     string `insert:"firstName"`
+	LastName      string `insert:"lastName"`
+	Email         string `insert:"email"`
+	Password      string `insert:"password"`
+	Role          string `insert:"role"`
+	EmailVerified bool   `insert:"emailVerified"`
+}
+
+// Default is a small catalogue with an admin and a regular user
+func Default() Data {
+	return Data{
+		Users: []User{
+			{FirstName: "Admin", LastName: "User", Email: "admin@example.com", Password: "Adm1n-pass", Role: types.RoleAdmin},
+			{FirstName: "Jane", LastName: "Doe", Email: "jane@example.com", Password: "Jane-pass1", Role: types.RoleUser},
+		},
+		Products: []types.Product{
+			{Name: "Chair", Description: "A wooden chair", Price: 4999, Quantity: 20},
+			{Name: "Table", Description: "A dining table", Price: 19900, Quantity: 5},
+			{Name: "Lamp", Description: "A desk lamp", Price: 2450, Quantity: 0},
+		},
+	}
+}
+
+// Seed inserts the users and products of data that aren't there yet, matching
+// users by email and products by name, so it can run on every start. It only
+// runs when config.Envs.Environment is explicitly development or test.
+func Seed(database *sql.DB, data Data) error {
+	if !slices.Contains(seedableEnvironments, config.Envs.Environment) {
+		return ErrSeedingNotAllowed
+	}
+
+	var users []interface{}
+	for _, u := range data.Users {
+		u.Email = utils.NormalizeEmail(u.Email)
+		exists, err := db.Exists(database, "users", &db.QueryOptions{Where: "email = ?", WhereArgs: []interface{}{u.Email}})
+		if err != nil {
+			return fmt.Errorf("failed to check user %s: %w", u.Email, err)
+		}
+		if exists {
+			continue
+		}
+
+		hashed, err := auth.HashPassword(u.Password)
+		if err != nil {
+			return err
+		}
+
+		role := u.Role
+		if role == "" {
+			role = types.RoleUser
+		}
+
+		users = append(users, userRow{
+			FirstName:     u.FirstName,
+			LastName:      u.LastName,
+			Email:         u.Email,
+			Password:      hashed,
+			Role:          role,
+			EmailVerified: true,
+		})
+	}
+
+	if _, err := db.BulkInsert[userRow](database, "users", users); err != nil {
+		return fmt.Errorf("failed to seed users: %w", err)
+	}
+
+	var products []interface{}
+	for _, p := range data.Products {
+		exists, err := db.Exists(database, "products", &db.QueryOptions{Where: "name = ?", WhereArgs: []interface{}{p.Name}})
+		if err != nil {
+			return fmt.Errorf("failed to check product %s: %w", p.Name, err)
+		}
+		if !exists {
+			products = append(products, p)
+		}
+	}
+
+	if _, err := db.BulkInsert[types.Product](database, "products", products); err != nil {
+		return fmt.Errorf("failed to seed products: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,66 @@
+package seed
+
+import (
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/Jay1570/learning-go/config"
+	"github.com/Jay1570/learning-go/testutil/fakedb"
+)
+
+func TestSeed(t *testing.T) {
+	defer func(environment string) { config.Envs.Environment = environment }(config.Envs.Environment)
+
+	t.Run("should refuse to seed outside development and test", func(t *testing.T) {
+		for _, environment := range []string{"", "production", "staging"} {
+			config.Envs.Environment = environment
+			conn, fake := fakedb.New(t, []string{"exists"}, []driver.Value{false})
+
+			if err := Seed(conn, Default()); !errors.Is(err, ErrSeedingNotAllowed) {
+				t.Errorf("%q: expected ErrSeedingNotAllowed, got %v", environment, err)
+			}
+			if len(fake.Queries) != 0 {
+				t.Errorf("%q: expected no statement, got %v", environment, fake.Queries)
+			}
+		}
+	})
+
+	t.Run("should insert the records that are missing", func(t *testing.T) {
+		config.Envs.Environment = "test"
+		conn, fake := fakedb.New(t, []string{"exists"}, []driver.Value{false})
+
+		if err := Seed(conn, Default()); err != nil {
+			t.Fatal(err)
+		}
+
+		if inserts := countInserts(fake.Queries); inserts != 5 {
+			t.Errorf("expected 2 users and 3 products inserted, got %d inserts", inserts)
+		}
+	})
+
+	t.Run("should insert nothing when run again", func(t *testing.T) {
+		config.Envs.Environment = "development"
+		conn, fake := fakedb.New(t, []string{"exists"}, []driver.Value{true})
+
+		if err := Seed(conn, Default()); err != nil {
+			t.Fatal(err)
+		}
+
+		if inserts := countInserts(fake.Queries); inserts != 0 {
+			t.Errorf("expected no inserts, got %v", fake.Queries)
+		}
+	})
+}
+
+func countInserts(queries []string) int {
+	inserts := 0
+	for _, query := range queries {
+		if strings.HasPrefix(query, "INSERT") {
+			inserts++
+		}
+	}
+
+	return inserts
+}
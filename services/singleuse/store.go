@@ -0,0 +1,83 @@
+// Package singleuse stores hashed tokens that can be redeemed once before
+// they expire, such as email verification and password reset tokens.
+package singleuse
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/Jay1570/learning-go/db"
+	"github.com/Jay1570/learning-go/services/auth"
+	"github.com/Jay1570/learning-go/types"
+)
+
+type Store struct {
+	db    *sql.DB
+	table string
+	ttl   time.Duration
+	// invalid is returned for unknown, used and expired tokens
+	invalid error
+}
+
+func NewStore(db *sql.DB, table string, ttl time.Duration, invalid error) *Store {
+	return &Store{db: db, table: table, ttl: ttl, invalid: invalid}
+}
+
+// Create issues a token for a user, only its hash is stored
+func (s *Store) Create(userID int) (string, error) {
+	token, err := auth.GenerateRandomToken()
+	if err != nil {
+		return "", fmt.Errorf("failed to generate token: %w", err)
+	}
+
+	_, err = db.InsertOne[types.SingleUseToken](s.db, s.table, types.SingleUseToken{
+		UserID:    userID,
+		TokenHash: auth.HashToken(token),
+		ExpiresAt: time.Now().Add(s.ttl),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// Consume marks the token used and returns its owner
+func (s *Store) Consume(token string) (int, error) {
+	var userID int
+	err := s.ConsumeWith(token, func(tx *sql.Tx, owner int) error {
+		userID = owner
+		return nil
+	})
+
+	return userID, err
+}
+
+// ConsumeWith marks the token used and runs fn for its owner in the same
+// transaction, so the token is only spent when fn succeeds
+func (s *Store) ConsumeWith(token string, fn func(tx *sql.Tx, userID int) error) error {
+	return db.WithTransaction(s.db, func(tx *sql.Tx) error {
+		stored, err := db.FindOne[types.SingleUseToken](tx, s.table, &db.QueryOptions{
+			Where:     "tokenHash = ?",
+			WhereArgs: []interface{}{auth.HashToken(token)},
+			LockMode:  db.ForUpdate,
+		})
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return s.invalid
+			}
+			return fmt.Errorf("failed to get token: %w", err)
+		}
+
+		if stored.UsedAt != nil || time.Now().After(stored.ExpiresAt) {
+			return s.invalid
+		}
+
+		if _, err := tx.Exec("UPDATE "+s.table+" SET usedAt = NOW() WHERE id = ?", stored.ID); err != nil {
+			return fmt.Errorf("failed to use token: %w", err)
+		}
+
+		return fn(tx, stored.UserID)
+	})
+}
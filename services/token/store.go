@@ -0,0 +1,92 @@
+package token
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/Jay1570/learning-go/config"
+	"github.com/Jay1570/learning-go/db"
+	"github.com/Jay1570/learning-go/services/auth"
+	"github.com/Jay1570/learning-go/types"
+)
+
+type Store struct {
+	db *sql.DB
+}
+
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db}
+}
+
+func (s *Store) CreateRefreshToken(userID int) (string, error) {
+	token, err := auth.GenerateRandomToken()
+	if err != nil {
+		return "", fmt.Errorf("failed to generate refresh token: %w", err)
+	}
+
+	expiration := time.Second * time.Duration(config.Envs.RefreshTokenExpirationInSeconds)
+
+	_, err = db.InsertOne[types.RefreshToken](s.db, "refresh_tokens", types.RefreshToken{
+		UserID:    userID,
+		TokenHash: auth.HashToken(token),
+		ExpiresAt: time.Now().Add(expiration),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// ConsumeRefreshToken revokes the given refresh token and returns its owner.
+// Presenting an already consumed token revokes every token of that user.
+func (s *Store) ConsumeRefreshToken(token string) (int, error) {
+	refreshToken, err := db.FindOne[types.RefreshToken](s.db, "refresh_tokens", &db.QueryOptions{
+		Where:     "tokenHash = ?",
+		WhereArgs: []interface{}{auth.HashToken(token)},
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return 0, types.ErrInvalidRefreshToken
+		}
+		return 0, fmt.Errorf("failed to get refresh token: %w", err)
+	}
+
+	if refreshToken.RevokedAt != nil {
+		if err := s.RevokeUserRefreshTokens(refreshToken.UserID); err != nil {
+			return 0, err
+		}
+		return 0, types.ErrRefreshTokenReused
+	}
+
+	if time.Now().After(refreshToken.ExpiresAt) {
+		return 0, types.ErrInvalidRefreshToken
+	}
+
+	result, err := s.db.Exec("UPDATE refresh_tokens SET revokedAt = NOW() WHERE id = ? AND revokedAt IS NULL", refreshToken.ID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to revoke refresh token: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to revoke refresh token: %w", err)
+	}
+
+	// Another request consumed the token between the lookup and the update
+	if affected == 0 {
+		return 0, types.ErrRefreshTokenReused
+	}
+
+	return refreshToken.UserID, nil
+}
+
+func (s *Store) RevokeUserRefreshTokens(userID int) error {
+	_, err := s.db.Exec("UPDATE refresh_tokens SET revokedAt = NOW() WHERE userId = ? AND revokedAt IS NULL", userID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke refresh tokens: %w", err)
+	}
+
+	return nil
+}
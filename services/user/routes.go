@@ -1,82 +1,189 @@
 package user
 
 import (
-	"fmt"
+	"errors"
+	"log/slog"
+	"math"
 	"net/http"
+	"net/url"
+	"time"
 
 	"github.com/Jay1570/learning-go/config"
+	"github.com/Jay1570/learning-go/db"
 	"github.com/Jay1570/learning-go/services/auth"
+	"github.com/Jay1570/learning-go/services/middleware"
 	"github.com/Jay1570/learning-go/types"
 	"github.com/Jay1570/learning-go/utils"
-	"github.com/go-playground/validator/v10"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
 )
 
 type Handler struct {
-	store types.UserStore
+	store             types.UserStore
+	tokenStore        types.RefreshTokenStore
+	verificationStore types.VerificationTokenStore
+	resetStore        types.PasswordResetTokenStore
+	mailer            types.EmailSender
 }
 
-func NewHandler(store types.UserStore) *Handler {
-	return &Handler{store: store}
+func NewHandler(store types.UserStore, tokenStore types.RefreshTokenStore, verificationStore types.VerificationTokenStore, resetStore types.PasswordResetTokenStore, mailer types.EmailSender) *Handler {
+	return &Handler{store: store, tokenStore: tokenStore, verificationStore: verificationStore, resetStore: resetStore, mailer: mailer}
 }
 
 func (h *Handler) RegisterRoutes(router *http.ServeMux) {
-	router.HandleFunc("POST /login", h.handleLogin)
-	router.HandleFunc("POST /register", h.handleRegister)
+	// Like an invalid ServeMux pattern, an invalid rate limit stops the
+	// server from starting
+	authLimiter := func() *middleware.RateLimiter {
+		limiter, err := middleware.NewRateLimiter(int(config.Envs.AuthRateLimit),
+			time.Duration(config.Envs.AuthRateLimitWindowInSeconds)*time.Second)
+		if err != nil {
+			panic(err)
+		}
+		return limiter
+	}
+	loginLimiter := authLimiter()
+	registerLimiter := authLimiter()
+	forgotPasswordLimiter := authLimiter()
+	idempotency := middleware.NewIdempotency(middleware.NewMemoryIdempotencyStore(),
+		time.Duration(config.Envs.IdempotencyKeyTTLInSeconds)*time.Second, config.Envs.MaxRequestBodyBytes)
+
+	router.Handle("POST /login", loginLimiter.Limit(http.HandlerFunc(h.handleLogin)))
+	router.Handle("POST /register", registerLimiter.Limit(idempotency.Handle(http.HandlerFunc(h.handleRegister))))
+	router.HandleFunc("POST /refresh", h.handleRefresh)
+	router.HandleFunc("GET /verify", h.handleVerifyEmail)
+	router.Handle("POST /forgot-password", forgotPasswordLimiter.Limit(http.HandlerFunc(h.handleForgotPassword)))
+	router.HandleFunc("POST /reset-password", h.handleResetPassword)
+	router.Handle("GET /me", auth.WithJWTAuth(http.HandlerFunc(h.handleGetMe), h.store))
+	router.Handle("PUT /me", auth.WithJWTAuth(http.HandlerFunc(h.handleUpdateMe), h.store))
+	router.Handle("DELETE /me", auth.WithJWTAuth(http.HandlerFunc(h.handleDeleteMe), h.store))
+	router.Handle("POST /change-password", auth.WithJWTAuth(http.HandlerFunc(h.handleChangePassword), h.store))
+
+	adminOnly := auth.RequireRole(types.RoleAdmin)
+	router.Handle("GET /users", auth.WithJWTAuth(adminOnly(http.HandlerFunc(h.handleGetUsers)), h.store))
 }
 
 func (h *Handler) handleLogin(w http.ResponseWriter, r *http.Request) {
 	var payload types.LoginUserPayload
 	if err := utils.ParseJSON(r, &payload); err != nil {
-		utils.WriteError(w, http.StatusBadRequest, err)
+		utils.WriteAPIError(w, err)
 		return
 	}
+	payload.Email = utils.NormalizeEmail(payload.Email)
 
 	if err := utils.Validate.Struct(payload); err != nil {
-		errors := err.(validator.ValidationErrors)
-		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid payload: %v", errors))
+		utils.WriteValidationError(w, err)
 		return
 	}
 
 	u, err := h.store.GetUserByEmail(payload.Email)
 	if err != nil {
-		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid email or password"))
+		utils.WriteAPIError(w, utils.BadRequest("invalid email or password"))
 		return
 	}
 
 	if !auth.ComparePasswords(u.Password, payload.Password) {
-		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid email or password"))
+		utils.WriteAPIError(w, utils.BadRequest("invalid email or password"))
+		return
+	}
+
+	if config.Envs.RequireEmailVerification && !u.EmailVerified {
+		utils.WriteAPIError(w, utils.Forbidden("email address is not verified"))
 		return
 	}
 
-	token, err := auth.CreateJWT(config.Envs.JWTSecret, u.ID)
+	token, err := auth.CreateJWT(config.Envs.JWTSecret, u.ID, u.Role)
 	if err != nil {
 		utils.WriteError(w, http.StatusInternalServerError, err)
 		return
 	}
 
+	refreshToken, err := h.tokenStore.CreateRefreshToken(u.ID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if config.Envs.AuthCookieEnabled {
+		utils.SetAuthCookie(w, token, time.Duration(config.Envs.JWTExpirationInSeconds)*time.Second)
+	}
+
 	response := map[string]any{
-		"status": http.StatusOK,
-		"token":  token,
+		"status":       http.StatusOK,
+		"token":        token,
+		"refreshToken": refreshToken,
 	}
 	utils.WriteJSON(w, response["status"].(int), response)
 }
 
-func (h *Handler) handleRegister(w http.ResponseWriter, r *http.Request) {
-	var payload types.RegisterUserPayload
+func (h *Handler) handleRefresh(w http.ResponseWriter, r *http.Request) {
+	var payload types.RefreshTokenPayload
 	if err := utils.ParseJSON(r, &payload); err != nil {
-		utils.WriteError(w, http.StatusBadRequest, err)
+		utils.WriteAPIError(w, err)
 		return
 	}
 
 	if err := utils.Validate.Struct(payload); err != nil {
-		errors := err.(validator.ValidationErrors)
-		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid payload: %v", errors))
+		utils.WriteValidationError(w, err)
 		return
 	}
 
-	_, err := h.store.GetUserByEmail(payload.Email)
-	if err == nil {
-		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("user with email %s already exists", payload.Email))
+	userID, err := h.tokenStore.ConsumeRefreshToken(payload.RefreshToken)
+	if err != nil {
+		if errors.Is(err, types.ErrInvalidRefreshToken) || errors.Is(err, types.ErrRefreshTokenReused) {
+			utils.WriteError(w, http.StatusUnauthorized, err)
+			return
+		}
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	// Look the user up again so role changes apply from the next refresh
+	u, err := h.store.GetUserByID(userID)
+	if err != nil {
+		if errors.Is(err, types.ErrUserNotFound) {
+			utils.WriteError(w, http.StatusUnauthorized, err)
+			return
+		}
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	token, err := auth.CreateJWT(config.Envs.JWTSecret, u.ID, u.Role)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	refreshToken, err := h.tokenStore.CreateRefreshToken(userID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if config.Envs.AuthCookieEnabled {
+		utils.SetAuthCookie(w, token, time.Duration(config.Envs.JWTExpirationInSeconds)*time.Second)
+	}
+
+	response := map[string]any{
+		"status":       http.StatusOK,
+		"token":        token,
+		"refreshToken": refreshToken,
+	}
+	utils.WriteJSON(w, response["status"].(int), response)
+}
+
+func (h *Handler) handleRegister(w http.ResponseWriter, r *http.Request) {
+	var payload types.RegisterUserPayload
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		utils.WriteAPIError(w, err)
+		return
+	}
+	payload.Email = utils.NormalizeEmail(payload.Email)
+
+	if !h.validateUserPayload(w, payload, payload.Email, 0) {
 		return
 	}
 
@@ -86,7 +193,7 @@ func (h *Handler) handleRegister(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = h.store.CreateUser(types.User{
+	userID, err := h.store.CreateUser(types.User{
 		FirstName: payload.FirstName,
 		LastName:  payload.LastName,
 		Email:     payload.Email,
@@ -97,9 +204,313 @@ func (h *Handler) handleRegister(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// The account exists at this point, so a failed email only gets logged
+	if err := h.sendVerificationEmail(userID, payload.Email); err != nil {
+		slog.Error("failed to send verification email", "user_id", userID, "error", err)
+	}
+
 	response := map[string]any{
 		"status":  http.StatusCreated,
 		"message": "User successfully created",
 	}
 	utils.WriteJSON(w, response["status"].(int), response)
 }
+
+func (h *Handler) handleChangePassword(w http.ResponseWriter, r *http.Request) {
+	u := auth.GetUserFromContext(r.Context())
+	if u == nil {
+		utils.WriteAPIError(w, utils.Unauthorized("unauthorized"))
+		return
+	}
+
+	var payload types.ChangePasswordPayload
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		utils.WriteAPIError(w, err)
+		return
+	}
+
+	if err := utils.Validate.Struct(payload); err != nil {
+		utils.WriteValidationError(w, err)
+		return
+	}
+
+	if !auth.ComparePasswords(u.Password, payload.CurrentPassword) {
+		utils.WriteAPIError(w, utils.BadRequest("current password is incorrect"))
+		return
+	}
+
+	hashedPassword, err := auth.HashPassword(payload.NewPassword)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if err := h.store.UpdateUserPassword(u.ID, hashedPassword); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	// Sessions opened with the old password must not outlive it
+	if err := h.tokenStore.RevokeUserRefreshTokens(u.ID); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	response := map[string]any{
+		"status":  http.StatusOK,
+		"message": "Password successfully changed",
+	}
+	utils.WriteJSON(w, response["status"].(int), response)
+}
+
+func (h *Handler) handleDeleteMe(w http.ResponseWriter, r *http.Request) {
+	u := auth.GetUserFromContext(r.Context())
+	if u == nil {
+		utils.WriteAPIError(w, utils.Unauthorized("unauthorized"))
+		return
+	}
+
+	var payload types.DeleteAccountPayload
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		utils.WriteAPIError(w, err)
+		return
+	}
+
+	if err := utils.Validate.Struct(payload); err != nil {
+		utils.WriteValidationError(w, err)
+		return
+	}
+
+	if !auth.ComparePasswords(u.Password, payload.Password) {
+		utils.WriteAPIError(w, utils.BadRequest("password is incorrect"))
+		return
+	}
+
+	// Refresh tokens go with the user; access tokens stop working because
+	// WithJWTAuth can no longer find the user
+	if err := h.store.DeleteUser(u.ID); err != nil {
+		if errors.Is(err, types.ErrUserHasOrders) {
+			utils.WriteAPIError(w, utils.Conflict(err.Error()))
+			return
+		}
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if config.Envs.AuthCookieEnabled {
+		utils.SetAuthCookie(w, "", -time.Second)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) handleGetUsers(w http.ResponseWriter, r *http.Request) {
+	page, err := utils.QueryInt(r, "page", 1, 1, math.MaxInt32)
+	if err != nil {
+		utils.WriteAPIError(w, err)
+		return
+	}
+
+	limit, err := utils.QueryInt(r, "limit", defaultPageSize, 1, maxPageSize)
+	if err != nil {
+		utils.WriteAPIError(w, err)
+		return
+	}
+
+	result, err := h.store.GetUsers(limit, (page-1)*limit)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	response := map[string]any{
+		"status": http.StatusOK,
+		"users":  utils.Paginate(result, page, limit),
+	}
+	utils.WriteJSON(w, response["status"].(int), response)
+}
+
+func (h *Handler) handleGetMe(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.GetUserIDFromContext(r.Context())
+	if !ok {
+		utils.WriteAPIError(w, utils.Unauthorized("unauthorized"))
+		return
+	}
+
+	u, err := h.store.GetUserByID(userID)
+	if err != nil {
+		if errors.Is(err, types.ErrUserNotFound) {
+			utils.WriteError(w, http.StatusNotFound, err)
+			return
+		}
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	response := map[string]any{
+		"status": http.StatusOK,
+		"user":   u,
+	}
+	utils.WriteJSON(w, response["status"].(int), response)
+}
+
+func (h *Handler) handleUpdateMe(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.GetUserIDFromContext(r.Context())
+	if !ok {
+		utils.WriteAPIError(w, utils.Unauthorized("unauthorized"))
+		return
+	}
+
+	var payload types.UpdateUserPayload
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		utils.WriteAPIError(w, err)
+		return
+	}
+	payload.Email = utils.NormalizeEmail(payload.Email)
+
+	if !h.validateUserPayload(w, payload, payload.Email, userID) {
+		return
+	}
+
+	u, err := h.store.UpdateUser(userID, payload)
+	if err != nil {
+		switch {
+		case errors.Is(err, db.ErrNoFieldsToUpdate):
+			utils.WriteError(w, http.StatusBadRequest, err)
+		case errors.Is(err, types.ErrUserNotFound):
+			utils.WriteError(w, http.StatusNotFound, err)
+		default:
+			utils.WriteError(w, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	response := map[string]any{
+		"status": http.StatusOK,
+		"user":   u,
+	}
+	utils.WriteJSON(w, response["status"].(int), response)
+}
+
+func (h *Handler) handleVerifyEmail(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		utils.WriteAPIError(w, utils.BadRequest("missing token"))
+		return
+	}
+
+	userID, err := h.verificationStore.ConsumeVerificationToken(token)
+	if err != nil {
+		if errors.Is(err, types.ErrInvalidVerificationToken) {
+			utils.WriteError(w, http.StatusBadRequest, err)
+			return
+		}
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if err := h.store.MarkEmailVerified(userID); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	response := map[string]any{
+		"status":  http.StatusOK,
+		"message": "Email successfully verified",
+	}
+	utils.WriteJSON(w, response["status"].(int), response)
+}
+
+func (h *Handler) sendVerificationEmail(userID int, email string) error {
+	token, err := h.verificationStore.CreateVerificationToken(userID)
+	if err != nil {
+		return err
+	}
+
+	link := utils.APIURL("v1", "/verify?token="+url.QueryEscape(token))
+
+	return h.mailer.SendEmail(email, "Verify your email address", "Open this link to verify your email address: "+link)
+}
+
+func (h *Handler) handleForgotPassword(w http.ResponseWriter, r *http.Request) {
+	var payload types.ForgotPasswordPayload
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		utils.WriteAPIError(w, err)
+		return
+	}
+	payload.Email = utils.NormalizeEmail(payload.Email)
+
+	if err := utils.Validate.Struct(payload); err != nil {
+		utils.WriteValidationError(w, err)
+		return
+	}
+
+	// Unknown emails get the same response so accounts can't be enumerated
+	u, err := h.store.GetUserByEmail(payload.Email)
+	if err == nil {
+		if err := h.sendPasswordResetEmail(u.ID, u.Email); err != nil {
+			slog.Error("failed to send password reset email", "user_id", u.ID, "error", err)
+		}
+	} else if !errors.Is(err, types.ErrUserNotFound) {
+		slog.Error("failed to look up user for password reset", "error", err)
+	}
+
+	response := map[string]any{
+		"status":  http.StatusOK,
+		"message": "If the email is registered, a password reset link has been sent",
+	}
+	utils.WriteJSON(w, response["status"].(int), response)
+}
+
+func (h *Handler) handleResetPassword(w http.ResponseWriter, r *http.Request) {
+	var payload types.ResetPasswordPayload
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		utils.WriteAPIError(w, err)
+		return
+	}
+
+	if err := utils.Validate.Struct(payload); err != nil {
+		utils.WriteValidationError(w, err)
+		return
+	}
+
+	hashedPassword, err := auth.HashPassword(payload.NewPassword)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	userID, err := h.resetStore.ResetPassword(payload.Token, hashedPassword)
+	if err != nil {
+		if errors.Is(err, types.ErrInvalidPasswordResetToken) {
+			utils.WriteError(w, http.StatusBadRequest, err)
+			return
+		}
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if err := h.tokenStore.RevokeUserRefreshTokens(userID); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	response := map[string]any{
+		"status":  http.StatusOK,
+		"message": "Password successfully reset",
+	}
+	utils.WriteJSON(w, response["status"].(int), response)
+}
+
+func (h *Handler) sendPasswordResetEmail(userID int, email string) error {
+	token, err := h.resetStore.CreatePasswordResetToken(userID)
+	if err != nil {
+		return err
+	}
+
+	// The API only takes the token by POST, the link opens the frontend page
+	// that asks for the new password and posts both
+	link := config.Envs.PasswordResetURL + "?token=" + url.QueryEscape(token)
+
+	return h.mailer.SendEmail(email, "Reset your password", "Open this link to choose a new password: "+link)
+}
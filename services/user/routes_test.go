@@ -2,18 +2,26 @@ package user
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
+	"github.com/Jay1570/learning-go/config"
+	"github.com/Jay1570/learning-go/db"
+	"github.com/Jay1570/learning-go/services/auth"
+	"github.com/Jay1570/learning-go/testutil"
 	"github.com/Jay1570/learning-go/types"
+	"github.com/Jay1570/learning-go/utils"
 )
 
 func TestUserService(t *testing.T) {
 	userStore := &mockUserStore{}
-	handler := NewHandler(userStore)
+	mailer := &mockEmailSender{}
+	handler := NewHandler(userStore, &mockRefreshTokenStore{}, &mockVerificationTokenStore{}, &mockPasswordResetTokenStore{}, mailer)
 
 	t.Run("should fail if user payload is invalid", func(t *testing.T) {
 		payload := types.RegisterUserPayload{
@@ -44,7 +52,7 @@ func TestUserService(t *testing.T) {
 			FirstName: "user",
 			LastName:  "123",
 			Email:     "valid@mail.com",
-			Password:  "asd",
+			Password:  "Str0ng-pass",
 		}
 		marshalled, _ := json.Marshal(payload)
 		req, err := http.NewRequest(http.MethodPost, "/register", bytes.NewBuffer(marshalled))
@@ -61,19 +69,549 @@ func TestUserService(t *testing.T) {
 		if rr.Code != http.StatusCreated {
 			t.Errorf("expexted status code %d, got %d", http.StatusCreated, rr.Code)
 		}
+
+		if len(mailer.sent) != 1 || mailer.sent[0] != "valid@mail.com" {
+			t.Errorf("expected a verification email to valid@mail.com, got %v", mailer.sent)
+		}
+	})
+
+	t.Run("should verify the email with a valid token", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodGet, "/verify?token=verification-token", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rr := httptest.NewRecorder()
+		router := http.NewServeMux()
+
+		router.HandleFunc("/verify", handler.handleVerifyEmail)
+		router.ServeHTTP(rr, req)
+
+		if rr.Code != http.StatusOK {
+			t.Errorf("expexted status code %d, got %d", http.StatusOK, rr.Code)
+		}
+	})
+
+	t.Run("should fail to verify with an invalid token", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodGet, "/verify?token=forged", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rr := httptest.NewRecorder()
+		router := http.NewServeMux()
+
+		router.HandleFunc("/verify", handler.handleVerifyEmail)
+		router.ServeHTTP(rr, req)
+
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("expexted status code %d, got %d", http.StatusBadRequest, rr.Code)
+		}
+	})
+
+	t.Run("should fail if the email is already registered", func(t *testing.T) {
+		handler := NewHandler(&mockUserStore{user: &types.User{ID: 1, Email: "valid@mail.com"}}, &mockRefreshTokenStore{}, &mockVerificationTokenStore{}, &mockPasswordResetTokenStore{}, &mockEmailSender{})
+
+		payload := types.RegisterUserPayload{
+			FirstName: "user",
+			LastName:  "123",
+			Email:     "valid@mail.com",
+			Password:  "Str0ng-pass",
+		}
+		marshalled, _ := json.Marshal(payload)
+		req, err := http.NewRequest(http.MethodPost, "/register", bytes.NewBuffer(marshalled))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rr := httptest.NewRecorder()
+		router := http.NewServeMux()
+
+		router.HandleFunc("/register", handler.handleRegister)
+		router.ServeHTTP(rr, req)
+
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("expexted status code %d, got %d", http.StatusBadRequest, rr.Code)
+		}
+
+		var body struct {
+			Error struct {
+				Code    string            `json:"code"`
+				Details map[string]string `json:"details"`
+			} `json:"error"`
+		}
+		if err := json.Unmarshal(rr.Body.Bytes(), &body); err != nil {
+			t.Fatal(err)
+		}
+		if body.Error.Code != utils.CodeValidationFailed {
+			t.Errorf("expected code %s, got %q", utils.CodeValidationFailed, body.Error.Code)
+		}
+		if _, ok := body.Error.Details["email"]; !ok {
+			t.Errorf("expected a field error for email, got %v", body.Error.Details)
+		}
+	})
+
+	t.Run("should return 500 if the existence check fails", func(t *testing.T) {
+		handler := NewHandler(&mockUserStore{err: fmt.Errorf("connection refused")}, &mockRefreshTokenStore{}, &mockVerificationTokenStore{}, &mockPasswordResetTokenStore{}, &mockEmailSender{})
+
+		payload := types.RegisterUserPayload{
+			FirstName: "user",
+			LastName:  "123",
+			Email:     "valid@mail.com",
+			Password:  "Str0ng-pass",
+		}
+		marshalled, _ := json.Marshal(payload)
+		req, err := http.NewRequest(http.MethodPost, "/register", bytes.NewBuffer(marshalled))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rr := httptest.NewRecorder()
+		router := http.NewServeMux()
+
+		router.HandleFunc("/register", handler.handleRegister)
+		router.ServeHTTP(rr, req)
+
+		if rr.Code != http.StatusInternalServerError {
+			t.Errorf("expexted status code %d, got %d", http.StatusInternalServerError, rr.Code)
+		}
+	})
+
+	t.Run("should not reveal whether an email is registered on forgot-password", func(t *testing.T) {
+		marshalled, _ := json.Marshal(types.ForgotPasswordPayload{Email: "unknown@mail.com"})
+		req, err := http.NewRequest(http.MethodPost, "/forgot-password", bytes.NewBuffer(marshalled))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rr := httptest.NewRecorder()
+		router := http.NewServeMux()
+
+		router.HandleFunc("/forgot-password", handler.handleForgotPassword)
+		router.ServeHTTP(rr, req)
+
+		if rr.Code != http.StatusOK {
+			t.Errorf("expexted status code %d, got %d", http.StatusOK, rr.Code)
+		}
+	})
+
+	t.Run("should reset the password with a valid token", func(t *testing.T) {
+		marshalled, _ := json.Marshal(types.ResetPasswordPayload{Token: "reset-token", NewPassword: "N3w-Str0ng-pass"})
+		req, err := http.NewRequest(http.MethodPost, "/reset-password", bytes.NewBuffer(marshalled))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rr := httptest.NewRecorder()
+		router := http.NewServeMux()
+
+		router.HandleFunc("/reset-password", handler.handleResetPassword)
+		router.ServeHTTP(rr, req)
+
+		if rr.Code != http.StatusOK {
+			t.Errorf("expexted status code %d, got %d", http.StatusOK, rr.Code)
+		}
+	})
+
+	t.Run("should fail to reset the password with an invalid token", func(t *testing.T) {
+		marshalled, _ := json.Marshal(types.ResetPasswordPayload{Token: "forged", NewPassword: "N3w-Str0ng-pass"})
+		req, err := http.NewRequest(http.MethodPost, "/reset-password", bytes.NewBuffer(marshalled))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rr := httptest.NewRecorder()
+		router := http.NewServeMux()
+
+		router.HandleFunc("/reset-password", handler.handleResetPassword)
+		router.ServeHTTP(rr, req)
+
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("expexted status code %d, got %d", http.StatusBadRequest, rr.Code)
+		}
+	})
+
+	t.Run("should reject an oversized body", func(t *testing.T) {
+		body := `{"firstName":"` + strings.Repeat("a", int(config.Envs.MaxRequestBodyBytes)) + `"}`
+		req, err := http.NewRequest(http.MethodPost, "/register", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rr := httptest.NewRecorder()
+		router := http.NewServeMux()
+
+		router.HandleFunc("/register", handler.handleRegister)
+		router.ServeHTTP(rr, req)
+
+		if rr.Code != http.StatusRequestEntityTooLarge {
+			t.Errorf("expexted status code %d, got %d", http.StatusRequestEntityTooLarge, rr.Code)
+		}
+	})
+
+	t.Run("should fail if the password is too weak", func(t *testing.T) {
+		payload := types.RegisterUserPayload{
+			FirstName: "user",
+			LastName:  "123",
+			Email:     "valid@mail.com",
+			Password:  "password",
+		}
+		marshalled, _ := json.Marshal(payload)
+		req, err := http.NewRequest(http.MethodPost, "/register", bytes.NewBuffer(marshalled))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rr := httptest.NewRecorder()
+		router := http.NewServeMux()
+
+		router.HandleFunc("/register", handler.handleRegister)
+		router.ServeHTTP(rr, req)
+
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("expexted status code %d, got %d", http.StatusBadRequest, rr.Code)
+		}
+	})
+
+	t.Run("should delete the account after confirming the password", func(t *testing.T) {
+		hashed, err := auth.HashPassword("Str0ng-pass")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, tt := range []struct {
+			password string
+			expected int
+		}{
+			{"Str0ng-pass", http.StatusNoContent},
+			{"wrong-pass", http.StatusBadRequest},
+		} {
+			marshalled, _ := json.Marshal(types.DeleteAccountPayload{Password: tt.password})
+			req, err := http.NewRequest(http.MethodDelete, "/me", bytes.NewBuffer(marshalled))
+			if err != nil {
+				t.Fatal(err)
+			}
+			req = req.WithContext(context.WithValue(req.Context(), auth.UserKey, &types.User{ID: 1, Password: hashed}))
+
+			rr := httptest.NewRecorder()
+			router := http.NewServeMux()
+
+			router.HandleFunc("DELETE /me", handler.handleDeleteMe)
+			router.ServeHTTP(rr, req)
+
+			if rr.Code != tt.expected {
+				t.Errorf("expexted status code %d, got %d", tt.expected, rr.Code)
+			}
+		}
+	})
+
+	t.Run("should list users for admins only", func(t *testing.T) {
+		handler := NewHandler(testutil.NewUserStore(
+			types.User{ID: 1, Email: "admin@mail.com", Password: "hash", Role: types.RoleAdmin},
+			types.User{ID: 2, Email: "user@mail.com", Password: "hash"},
+		), &mockRefreshTokenStore{}, &mockVerificationTokenStore{}, &mockPasswordResetTokenStore{}, &mockEmailSender{})
+		listing := auth.RequireRole(types.RoleAdmin)(http.HandlerFunc(handler.handleGetUsers))
+
+		for _, tt := range []struct {
+			role     string
+			expected int
+		}{
+			{types.RoleAdmin, http.StatusOK},
+			{types.RoleUser, http.StatusForbidden},
+		} {
+			req, err := http.NewRequest(http.MethodGet, "/users?limit=1", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req = req.WithContext(context.WithValue(req.Context(), auth.RoleKey, tt.role))
+
+			rr := httptest.NewRecorder()
+			listing.ServeHTTP(rr, req)
+
+			if rr.Code != tt.expected {
+				t.Errorf("expexted status code %d, got %d", tt.expected, rr.Code)
+			}
+
+			if tt.expected == http.StatusOK {
+				if strings.Contains(rr.Body.String(), "hash") {
+					t.Error("expected no password hashes in the listing")
+				}
+
+				var body struct {
+					Users utils.PaginatedResponse[types.User] `json:"users"`
+				}
+				if err := json.Unmarshal(rr.Body.Bytes(), &body); err != nil {
+					t.Fatal(err)
+				}
+				if body.Users.TotalCount != 2 || len(body.Users.Data) != 1 || !body.Users.HasNext {
+					t.Errorf("unexpected page %+v", body.Users)
+				}
+			}
+		}
+	})
+
+	t.Run("should treat emails differing only in case as the same account", func(t *testing.T) {
+		handler := NewHandler(testutil.NewUserStore(types.User{Email: "valid@mail.com"}),
+			&mockRefreshTokenStore{}, &mockVerificationTokenStore{}, &mockPasswordResetTokenStore{}, &mockEmailSender{})
+
+		payload := types.RegisterUserPayload{
+			FirstName: "user",
+			LastName:  "123",
+			Email:     "  Valid@Mail.COM ",
+			Password:  "Str0ng-pass",
+		}
+		marshalled, _ := json.Marshal(payload)
+		req, err := http.NewRequest(http.MethodPost, "/register", bytes.NewBuffer(marshalled))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rr := httptest.NewRecorder()
+		router := http.NewServeMux()
+
+		router.HandleFunc("/register", handler.handleRegister)
+		router.ServeHTTP(rr, req)
+
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("expexted status code %d, got %d", http.StatusBadRequest, rr.Code)
+		}
+	})
+
+	t.Run("should rotate refresh tokens", func(t *testing.T) {
+		defer func(secret string) { config.Envs.JWTSecret = secret }(config.Envs.JWTSecret)
+		config.Envs.JWTSecret = "test-secret"
+
+		tokenStore := testutil.NewRefreshTokenStore()
+		handler := NewHandler(testutil.NewUserStore(types.User{Email: "valid@mail.com"}),
+			tokenStore, &mockVerificationTokenStore{}, &mockPasswordResetTokenStore{}, &mockEmailSender{})
+
+		refresh := func(token string) *httptest.ResponseRecorder {
+			marshalled, _ := json.Marshal(types.RefreshTokenPayload{RefreshToken: token})
+			req, err := http.NewRequest(http.MethodPost, "/refresh", bytes.NewBuffer(marshalled))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rr := httptest.NewRecorder()
+			router := http.NewServeMux()
+
+			router.HandleFunc("/refresh", handler.handleRefresh)
+			router.ServeHTTP(rr, req)
+			return rr
+		}
+
+		original, err := tokenStore.CreateRefreshToken(1)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rr := refresh(original)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expexted status code %d, got %d", http.StatusOK, rr.Code)
+		}
+
+		var body struct {
+			Token        string `json:"token"`
+			RefreshToken string `json:"refreshToken"`
+		}
+		if err := json.Unmarshal(rr.Body.Bytes(), &body); err != nil {
+			t.Fatal(err)
+		}
+		if body.Token == "" || body.RefreshToken == "" || body.RefreshToken == original {
+			t.Fatalf("expected a new token pair, got %+v", body)
+		}
+
+		// The original is spent, and presenting it again revokes the new one
+		if rr := refresh(original); rr.Code != http.StatusUnauthorized {
+			t.Errorf("expexted status code %d, got %d", http.StatusUnauthorized, rr.Code)
+		}
+		if rr := refresh(body.RefreshToken); rr.Code != http.StatusUnauthorized {
+			t.Errorf("expexted status code %d, got %d", http.StatusUnauthorized, rr.Code)
+		}
+	})
+
+	t.Run("should reject an unknown or expired refresh token", func(t *testing.T) {
+		defer func(expiry int64) { config.Envs.RefreshTokenExpirationInSeconds = expiry }(config.Envs.RefreshTokenExpirationInSeconds)
+
+		tokenStore := testutil.NewRefreshTokenStore()
+		handler := NewHandler(testutil.NewUserStore(types.User{Email: "valid@mail.com"}),
+			tokenStore, &mockVerificationTokenStore{}, &mockPasswordResetTokenStore{}, &mockEmailSender{})
+
+		config.Envs.RefreshTokenExpirationInSeconds = -1
+		expired, err := tokenStore.CreateRefreshToken(1)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, token := range []string{"forged", expired} {
+			marshalled, _ := json.Marshal(types.RefreshTokenPayload{RefreshToken: token})
+			req, err := http.NewRequest(http.MethodPost, "/refresh", bytes.NewBuffer(marshalled))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rr := httptest.NewRecorder()
+			router := http.NewServeMux()
+
+			router.HandleFunc("/refresh", handler.handleRefresh)
+			router.ServeHTTP(rr, req)
+
+			if rr.Code != http.StatusUnauthorized {
+				t.Errorf("%s: expexted status code %d, got %d", token, http.StatusUnauthorized, rr.Code)
+			}
+		}
+	})
+
+	t.Run("should change the password and revoke the refresh tokens", func(t *testing.T) {
+		hashed, err := auth.HashPassword("0ld-Str0ng-pass")
+		if err != nil {
+			t.Fatal(err)
+		}
+		userStore := testutil.NewUserStore(types.User{Email: "valid@mail.com", Password: hashed})
+		tokenStore := testutil.NewRefreshTokenStore()
+		handler := NewHandler(userStore, tokenStore, &mockVerificationTokenStore{}, &mockPasswordResetTokenStore{}, &mockEmailSender{})
+
+		refreshToken, err := tokenStore.CreateRefreshToken(1)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, tt := range []struct {
+			name     string
+			payload  types.ChangePasswordPayload
+			expected int
+		}{
+			{"wrong current password", types.ChangePasswordPayload{CurrentPassword: "Wr0ng-pass", NewPassword: "N3w-Str0ng-pass"}, http.StatusBadRequest},
+			{"weak new password", types.ChangePasswordPayload{CurrentPassword: "0ld-Str0ng-pass", NewPassword: "weak"}, http.StatusBadRequest},
+			{"valid change", types.ChangePasswordPayload{CurrentPassword: "0ld-Str0ng-pass", NewPassword: "N3w-Str0ng-pass"}, http.StatusOK},
+		} {
+			u, err := userStore.GetUserByID(1)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			marshalled, _ := json.Marshal(tt.payload)
+			req, err := http.NewRequest(http.MethodPost, "/change-password", bytes.NewBuffer(marshalled))
+			if err != nil {
+				t.Fatal(err)
+			}
+			req = req.WithContext(context.WithValue(req.Context(), auth.UserKey, u))
+
+			rr := httptest.NewRecorder()
+			router := http.NewServeMux()
+
+			router.HandleFunc("/change-password", handler.handleChangePassword)
+			router.ServeHTTP(rr, req)
+
+			if rr.Code != tt.expected {
+				t.Errorf("%s: expexted status code %d, got %d", tt.name, tt.expected, rr.Code)
+			}
+		}
+
+		u, err := userStore.GetUserByID(1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !auth.ComparePasswords(u.Password, "N3w-Str0ng-pass") {
+			t.Error("expected the new password to be stored")
+		}
+
+		if _, err := tokenStore.ConsumeRefreshToken(refreshToken); err == nil {
+			t.Error("expected the refresh token to be revoked")
+		}
 	})
 }
 
-type mockUserStore struct{}
+type mockUserStore struct {
+	user *types.User
+	err  error
+}
 
 func (m *mockUserStore) GetUserByEmail(email string) (*types.User, error) {
-	return &types.User{}, fmt.Errorf("user not found")
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	if m.user == nil {
+		return nil, types.ErrUserNotFound
+	}
+
+	return m.user, nil
 }
 
 func (m *mockUserStore) GetUserByID(id int) (*types.User, error) {
 	return nil, nil
 }
 
-func (m *mockUserStore) CreateUser(types.User) error {
+func (m *mockUserStore) CreateUser(types.User) (int, error) {
+	return 1, nil
+}
+
+func (m *mockUserStore) UpdateUser(id int, payload types.UpdateUserPayload) (*types.User, error) {
+	return &types.User{ID: id}, nil
+}
+
+func (m *mockUserStore) UpdateUserPassword(id int, password string) error {
+	return nil
+}
+
+func (m *mockUserStore) MarkEmailVerified(id int) error {
+	return nil
+}
+
+func (m *mockUserStore) DeleteUser(id int) error {
 	return nil
 }
+
+func (m *mockUserStore) GetUsers(limit, offset int) (*db.CountResult[types.User], error) {
+	return &db.CountResult[types.User]{Data: []types.User{}}, nil
+}
+
+type mockRefreshTokenStore struct{}
+
+func (m *mockRefreshTokenStore) CreateRefreshToken(userID int) (string, error) {
+	return "refresh-token", nil
+}
+
+func (m *mockRefreshTokenStore) ConsumeRefreshToken(token string) (int, error) {
+	return 0, types.ErrInvalidRefreshToken
+}
+
+func (m *mockRefreshTokenStore) RevokeUserRefreshTokens(userID int) error {
+	return nil
+}
+
+type mockVerificationTokenStore struct{}
+
+func (m *mockVerificationTokenStore) CreateVerificationToken(userID int) (string, error) {
+	return "verification-token", nil
+}
+
+func (m *mockVerificationTokenStore) ConsumeVerificationToken(token string) (int, error) {
+	if token != "verification-token" {
+		return 0, types.ErrInvalidVerificationToken
+	}
+	return 1, nil
+}
+
+type mockEmailSender struct {
+	sent []string
+}
+
+func (m *mockEmailSender) SendEmail(to, subject, body string) error {
+	m.sent = append(m.sent, to)
+	return nil
+}
+
+type mockPasswordResetTokenStore struct{}
+
+func (m *mockPasswordResetTokenStore) CreatePasswordResetToken(userID int) (string, error) {
+	return "reset-token", nil
+}
+
+func (m *mockPasswordResetTokenStore) ResetPassword(token, hashedPassword string) (int, error) {
+	if token != "reset-token" {
+		return 0, types.ErrInvalidPasswordResetToken
+	}
+	return 1, nil
+}
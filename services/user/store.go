@@ -2,10 +2,22 @@ package user
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/Jay1570/learning-go/db"
 	"github.com/Jay1570/learning-go/types"
+	"github.com/Jay1570/learning-go/utils"
+	"github.com/go-sql-driver/mysql"
+)
+
+// mysqlErrRowIsReferenced is raised when a foreign key blocks a delete
+const mysqlErrRowIsReferenced = 1451
+
+const (
+	readRetryAttempts = 3
+	readRetryBackoff  = 50 * time.Millisecond
 )
 
 type Store struct {
@@ -19,12 +31,12 @@ func NewStore(db *sql.DB) *Store {
 func (s *Store) GetUserByEmail(email string) (*types.User, error) {
 	user, err := db.FindOne[types.User](s.db, "users", &db.QueryOptions{
 		Where:     "email = ?",
-		WhereArgs: []interface{}{email},
+		WhereArgs: []interface{}{utils.NormalizeEmail(email)},
 	})
 
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("user not found")
+			return nil, types.ErrUserNotFound
 		}
 		return nil, fmt.Errorf("failed to get user by email: %w", err)
 	}
@@ -32,11 +44,18 @@ func (s *Store) GetUserByEmail(email string) (*types.User, error) {
 	return user, nil
 }
 
+// GetUserByID runs on every authenticated request, so transient errors get
+// a couple of retries; it is a plain read and safe to repeat
 func (s *Store) GetUserByID(id int) (*types.User, error) {
-	user, err := db.FindByPK[types.User](s.db, "users", id)
+	var user *types.User
+	err := db.WithRetry(func() error {
+		var err error
+		user, err = db.FindByPK[types.User](s.db, "users", id)
+		return err
+	}, readRetryAttempts, readRetryBackoff)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("user not found")
+			return nil, types.ErrUserNotFound
 		}
 		return nil, fmt.Errorf("failed to get user by id: %w", err)
 	}
@@ -44,7 +63,104 @@ func (s *Store) GetUserByID(id int) (*types.User, error) {
 	return user, nil
 }
 
-func (s *Store) CreateUser(user types.User) error {
-	_, err := db.InsertOne[types.User](s.db, "users", user)
-	return err
+// GetUsers lists one page of users by id. The password hash is never selected.
+func (s *Store) GetUsers(limit, offset int) (*db.CountResult[types.User], error) {
+	result, err := db.FindAllAndCount[types.User](s.db, "users", &db.QueryOptions{
+		Select: "id, firstName, lastName, email, role, emailVerified, createdAt",
+		Order:  []db.OrderByClause{db.Asc("id")},
+		Limit:  limit,
+		Offset: offset,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get users: %w", err)
+	}
+
+	return result, nil
+}
+
+func (s *Store) CreateUser(user types.User) (int, error) {
+	user.Email = utils.NormalizeEmail(user.Email)
+	id, err := db.InsertOne[types.User](s.db, "users", user)
+	return int(id), err
+}
+
+func (s *Store) UpdateUser(id int, payload types.UpdateUserPayload) (*types.User, error) {
+	payload.Email = utils.NormalizeEmail(payload.Email)
+	users, err := db.UpdateData[types.User](s.db, "users", payload, &db.QueryOptions{
+		Where:     "id = ?",
+		WhereArgs: []interface{}{id},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(users) == 0 {
+		return nil, types.ErrUserNotFound
+	}
+
+	return &users[0], nil
+}
+
+func (s *Store) UpdateUserPassword(id int, password string) error {
+	users, err := db.UpdateData[types.User](s.db, "users", types.User{Password: password}, &db.QueryOptions{
+		Where:     "id = ?",
+		WhereArgs: []interface{}{id},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update password: %w", err)
+	}
+
+	if len(users) == 0 {
+		return types.ErrUserNotFound
+	}
+
+	return nil
+}
+
+func (s *Store) MarkEmailVerified(id int) error {
+	_, err := s.db.Exec("UPDATE users SET emailVerified = TRUE WHERE id = ?", id)
+	if err != nil {
+		return fmt.Errorf("failed to mark email verified: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteUser removes the user together with their refresh tokens. Users with
+// orders are kept for the order history and get ErrUserHasOrders.
+func (s *Store) DeleteUser(id int) error {
+	err := db.WithTransaction(s.db, func(tx *sql.Tx) error {
+		if _, err := db.DeleteCount(tx, "refresh_tokens", &db.QueryOptions{
+			Where:     "userId = ?",
+			WhereArgs: []interface{}{id},
+		}); err != nil {
+			return err
+		}
+
+		deleted, err := db.DeleteCount(tx, "users", &db.QueryOptions{
+			Where:     "id = ?",
+			WhereArgs: []interface{}{id},
+		})
+		if err != nil {
+			return err
+		}
+
+		if deleted == 0 {
+			return types.ErrUserNotFound
+		}
+
+		return nil
+	})
+	if err != nil {
+		var mysqlErr *mysql.MySQLError
+		if errors.As(err, &mysqlErr) && mysqlErr.Number == mysqlErrRowIsReferenced {
+			return types.ErrUserHasOrders
+		}
+		if errors.Is(err, types.ErrUserNotFound) {
+			return err
+		}
+		return fmt.Errorf("failed to delete user: %w", err)
+	}
+
+	return nil
 }
@@ -0,0 +1,40 @@
+package user
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/Jay1570/learning-go/types"
+	"github.com/Jay1570/learning-go/utils"
+)
+
+// validateUserPayload runs the struct validation and then checks that email
+// isn't already owned by an account other than userID (0 for a new account).
+// It writes the error response itself and reports whether the handler may
+// continue, so register and profile update fail the same way.
+func (h *Handler) validateUserPayload(w http.ResponseWriter, payload any, email string, userID int) bool {
+	if err := utils.Validate.Struct(payload); err != nil {
+		utils.WriteValidationError(w, err)
+		return false
+	}
+
+	if email == "" {
+		return true
+	}
+
+	existing, err := h.store.GetUserByEmail(email)
+	if err != nil {
+		if errors.Is(err, types.ErrUserNotFound) {
+			return true
+		}
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return false
+	}
+
+	if existing.ID != userID {
+		utils.WriteFieldErrors(w, map[string]string{"email": "is already taken"})
+		return false
+	}
+
+	return true
+}
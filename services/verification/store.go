@@ -0,0 +1,29 @@
+package verification
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/Jay1570/learning-go/config"
+	"github.com/Jay1570/learning-go/services/singleuse"
+	"github.com/Jay1570/learning-go/types"
+)
+
+type Store struct {
+	tokens *singleuse.Store
+}
+
+func NewStore(db *sql.DB) *Store {
+	expiration := time.Second * time.Duration(config.Envs.VerificationTokenExpirationInSeconds)
+	return &Store{tokens: singleuse.NewStore(db, "email_verification_tokens", expiration, types.ErrInvalidVerificationToken)}
+}
+
+func (s *Store) CreateVerificationToken(userID int) (string, error) {
+	return s.tokens.Create(userID)
+}
+
+// ConsumeVerificationToken marks the token used and returns its owner. Each
+// token can only be used once.
+func (s *Store) ConsumeVerificationToken(token string) (int, error) {
+	return s.tokens.Consume(token)
+}
@@ -0,0 +1,168 @@
+// Package fakedb is a minimal database/sql driver that serves canned rows and
+// records every statement it receives, so stores can be tested without a real
+// database.
+package fakedb
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"sync"
+	"testing"
+)
+
+type fakeDriver struct{}
+
+// DB is the state behind a fake connection. Every query returns Rows; set the
+// other fields before running statements to script the results.
+type DB struct {
+	mu      sync.Mutex
+	Columns []string
+	Rows    [][]driver.Value
+	Queries []string
+	Args    [][]driver.Value
+
+	// RowsAffected is used in turn as the result of each Exec; once it runs
+	// out, Exec reports the number of Rows
+	RowsAffected []int64
+	// Err fails every statement
+	Err error
+
+	Commits   int
+	Rollbacks int
+}
+
+var (
+	dbsMu sync.Mutex
+	dbs   = map[string]*DB{}
+)
+
+func init() {
+	sql.Register("fakedb", fakeDriver{})
+}
+
+// New opens a database whose queries all return the given rows
+func New(t *testing.T, columns []string, rows ...[]driver.Value) (*sql.DB, *DB) {
+	t.Helper()
+
+	fake := &DB{Columns: columns, Rows: rows}
+
+	dbsMu.Lock()
+	name := fmt.Sprintf("%s-%d", t.Name(), len(dbs))
+	dbs[name] = fake
+	dbsMu.Unlock()
+
+	conn, err := sql.Open("fakedb", name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return conn, fake
+}
+
+func (fakeDriver) Open(name string) (driver.Conn, error) {
+	dbsMu.Lock()
+	defer dbsMu.Unlock()
+
+	fake, ok := dbs[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown fake database %q", name)
+	}
+
+	return &fakeConn{db: fake}, nil
+}
+
+type fakeConn struct {
+	db *DB
+}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return &fakeStmt{db: c.db, query: query}, nil
+}
+
+func (c *fakeConn) Close() error { return nil }
+
+func (c *fakeConn) Begin() (driver.Tx, error) { return fakeTx{db: c.db}, nil }
+
+type fakeTx struct {
+	db *DB
+}
+
+func (tx fakeTx) Commit() error {
+	tx.db.mu.Lock()
+	defer tx.db.mu.Unlock()
+
+	tx.db.Commits++
+	return nil
+}
+
+func (tx fakeTx) Rollback() error {
+	tx.db.mu.Lock()
+	defer tx.db.mu.Unlock()
+
+	tx.db.Rollbacks++
+	return nil
+}
+
+type fakeStmt struct {
+	db    *DB
+	query string
+}
+
+func (s *fakeStmt) Close() error  { return nil }
+func (s *fakeStmt) NumInput() int { return -1 }
+
+func (s *fakeStmt) record(args []driver.Value) {
+	s.db.Queries = append(s.db.Queries, s.query)
+	s.db.Args = append(s.db.Args, args)
+}
+
+func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	s.record(args)
+	if s.db.Err != nil {
+		return nil, s.db.Err
+	}
+
+	affected := int64(len(s.db.Rows))
+	if len(s.db.RowsAffected) > 0 {
+		affected, s.db.RowsAffected = s.db.RowsAffected[0], s.db.RowsAffected[1:]
+	}
+
+	return driver.RowsAffected(affected), nil
+}
+
+func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	s.record(args)
+	if s.db.Err != nil {
+		return nil, s.db.Err
+	}
+
+	return &fakeRows{columns: s.db.Columns, rows: s.db.Rows}, nil
+}
+
+type fakeRows struct {
+	columns []string
+	rows    [][]driver.Value
+	pos     int
+}
+
+func (r *fakeRows) Columns() []string { return r.columns }
+func (r *fakeRows) Close() error      { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.pos >= len(r.rows) {
+		return io.EOF
+	}
+
+	copy(dest, r.rows[r.pos])
+	r.pos++
+	return nil
+}
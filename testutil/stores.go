@@ -0,0 +1,509 @@
+// Package testutil provides in-memory implementations of the stores so
+// handlers can be tested without a database.
+package testutil
+
+import (
+	"cmp"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Jay1570/learning-go/config"
+	"github.com/Jay1570/learning-go/db"
+	"github.com/Jay1570/learning-go/types"
+	"github.com/Jay1570/learning-go/utils"
+)
+
+// ErrDuplicateEmail mirrors the unique key on users.email
+var ErrDuplicateEmail = errors.New("duplicate email")
+
+// UserStore is an in-memory types.UserStore
+type UserStore struct {
+	mu     sync.Mutex
+	nextID int
+	users  map[int]types.User
+}
+
+func NewUserStore(users ...types.User) *UserStore {
+	s := &UserStore{nextID: 1, users: map[int]types.User{}}
+	for _, u := range users {
+		if _, err := s.CreateUser(u); err != nil {
+			panic(err)
+		}
+	}
+	return s
+}
+
+func (s *UserStore) GetUserByEmail(email string) (*types.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	email = utils.NormalizeEmail(email)
+
+	for _, u := range s.users {
+		if u.Email == email {
+			return &u, nil
+		}
+	}
+
+	return nil, types.ErrUserNotFound
+}
+
+func (s *UserStore) GetUserByID(id int) (*types.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, ok := s.users[id]
+	if !ok {
+		return nil, types.ErrUserNotFound
+	}
+
+	return &u, nil
+}
+
+// CreateUser assigns the next id, keeping an id already set on the user
+func (s *UserStore) CreateUser(user types.User) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user.Email = utils.NormalizeEmail(user.Email)
+
+	if s.emailTaken(user.Email, 0) {
+		return 0, ErrDuplicateEmail
+	}
+
+	if user.ID == 0 {
+		user.ID = s.nextID
+	}
+	if user.ID >= s.nextID {
+		s.nextID = user.ID + 1
+	}
+	if user.Role == "" {
+		user.Role = types.RoleUser
+	}
+	if user.CreatedAt.IsZero() {
+		user.CreatedAt = time.Now()
+	}
+
+	s.users[user.ID] = user
+
+	return user.ID, nil
+}
+
+func (s *UserStore) UpdateUser(id int, payload types.UpdateUserPayload) (*types.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	payload.Email = utils.NormalizeEmail(payload.Email)
+
+	u, ok := s.users[id]
+	if !ok {
+		return nil, types.ErrUserNotFound
+	}
+
+	if payload.FirstName == "" && payload.LastName == "" && payload.Email == "" {
+		return nil, db.ErrNoFieldsToUpdate
+	}
+
+	if payload.Email != "" && s.emailTaken(payload.Email, id) {
+		return nil, ErrDuplicateEmail
+	}
+
+	if payload.FirstName != "" {
+		u.FirstName = payload.FirstName
+	}
+	if payload.LastName != "" {
+		u.LastName = payload.LastName
+	}
+	if payload.Email != "" {
+		u.Email = payload.Email
+	}
+
+	s.users[id] = u
+
+	return &u, nil
+}
+
+func (s *UserStore) UpdateUserPassword(id int, password string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, ok := s.users[id]
+	if !ok {
+		return types.ErrUserNotFound
+	}
+
+	u.Password = password
+	s.users[id] = u
+
+	return nil
+}
+
+func (s *UserStore) MarkEmailVerified(id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, ok := s.users[id]
+	if !ok {
+		return types.ErrUserNotFound
+	}
+
+	u.EmailVerified = true
+	s.users[id] = u
+
+	return nil
+}
+
+func (s *UserStore) DeleteUser(id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.users[id]; !ok {
+		return types.ErrUserNotFound
+	}
+
+	delete(s.users, id)
+
+	return nil
+}
+
+func (s *UserStore) GetUsers(limit, offset int) (*db.CountResult[types.User], error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	users := make([]types.User, 0, len(s.users))
+	for _, u := range s.users {
+		u.Password = ""
+		users = append(users, u)
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].ID < users[j].ID })
+
+	result := &db.CountResult[types.User]{Data: []types.User{}, Count: len(users)}
+	if offset < len(users) {
+		result.Data = users[offset:min(offset+limit, len(users))]
+	}
+
+	return result, nil
+}
+
+// emailTaken reports whether a user other than exceptID owns email
+func (s *UserStore) emailTaken(email string, exceptID int) bool {
+	for id, u := range s.users {
+		if u.Email == email && id != exceptID {
+			return true
+		}
+	}
+	return false
+}
+
+// ProductStore is an in-memory types.ProductStore
+type ProductStore struct {
+	mu       sync.Mutex
+	nextID   int
+	products map[int]types.Product
+}
+
+func NewProductStore(products ...types.Product) *ProductStore {
+	s := &ProductStore{nextID: 1, products: map[int]types.Product{}}
+	for _, p := range products {
+		if err := s.CreateProduct(p); err != nil {
+			panic(err)
+		}
+	}
+	return s
+}
+
+func (s *ProductStore) GetProducts() ([]types.Product, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.sorted(), nil
+}
+
+func (s *ProductStore) GetProductsPaginated(filter types.ProductFilter, limit, offset int) (*db.CountResult[types.Product], error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var products []types.Product
+	for _, p := range s.sorted() {
+		if matchesFilter(p, filter) {
+			products = append(products, p)
+		}
+	}
+	sortProducts(products, filter.Order)
+	result := &db.CountResult[types.Product]{Data: []types.Product{}, Count: len(products)}
+
+	if offset < len(products) {
+		end := min(offset+limit, len(products))
+		result.Data = products[offset:end]
+	}
+
+	return result, nil
+}
+
+func (s *ProductStore) GetLowStockProducts(threshold int) ([]types.Product, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	products := []types.Product{}
+	for _, p := range s.sorted() {
+		if p.Quantity < threshold {
+			products = append(products, p)
+		}
+	}
+	sort.SliceStable(products, func(i, j int) bool { return products[i].Quantity < products[j].Quantity })
+
+	return products, nil
+}
+
+func (s *ProductStore) GetProductStats() (*types.ProductStats, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stats := &types.ProductStats{Count: len(s.products)}
+	for _, p := range s.products {
+		stats.InventoryValue += p.Price * types.Money(p.Quantity)
+		stats.AveragePrice += p.Price.Float64()
+		if p.Quantity == 0 {
+			stats.OutOfStock++
+		}
+	}
+	if stats.Count > 0 {
+		stats.AveragePrice /= float64(stats.Count)
+	}
+
+	return stats, nil
+}
+
+func (s *ProductStore) GetProductByID(id int) (*types.Product, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p, ok := s.products[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+
+	return &p, nil
+}
+
+// CreateProduct assigns the next id, keeping an id already set on the product
+func (s *ProductStore) CreateProduct(product types.Product) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if product.ID == 0 {
+		product.ID = s.nextID
+	}
+	if product.ID >= s.nextID {
+		s.nextID = product.ID + 1
+	}
+	if s.nameTaken(product.Name, product.ID) {
+		return types.ErrDuplicateProduct
+	}
+	if product.CreatedAt.IsZero() {
+		product.CreatedAt = time.Now()
+	}
+
+	s.products[product.ID] = product
+
+	return nil
+}
+
+func (s *ProductStore) UpdateProduct(id int, payload types.UpdateProductPayload) (*types.Product, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if payload.Name == nil && payload.Description == nil && payload.Image == nil &&
+		payload.Price == nil && payload.Quantity == nil {
+		return nil, db.ErrNoFieldsToUpdate
+	}
+
+	p, ok := s.products[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+
+	if payload.Name != nil {
+		if s.nameTaken(*payload.Name, id) {
+			return nil, types.ErrDuplicateProduct
+		}
+		p.Name = *payload.Name
+	}
+	if payload.Description != nil {
+		p.Description = *payload.Description
+	}
+	if payload.Image != nil {
+		p.Image = *payload.Image
+	}
+	if payload.Price != nil {
+		p.Price = *payload.Price
+	}
+	if payload.Quantity != nil {
+		p.Quantity = *payload.Quantity
+	}
+
+	s.products[id] = p
+
+	return &p, nil
+}
+
+func (s *ProductStore) DeleteProduct(id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.products[id]; !ok {
+		return sql.ErrNoRows
+	}
+
+	delete(s.products, id)
+
+	return nil
+}
+
+// nameTaken mirrors the unique key on products.name
+func (s *ProductStore) nameTaken(name string, exceptID int) bool {
+	for _, p := range s.products {
+		if p.Name == name && p.ID != exceptID {
+			return true
+		}
+	}
+
+	return false
+}
+
+// matchesFilter mirrors the conditions the SQL store builds from a filter
+func matchesFilter(p types.Product, filter types.ProductFilter) bool {
+	if q := strings.ToLower(strings.TrimSpace(filter.Query)); q != "" &&
+		!strings.Contains(strings.ToLower(p.Name), q) && !strings.Contains(strings.ToLower(p.Description), q) {
+		return false
+	}
+	if filter.MinPrice != nil && p.Price.Float64() < *filter.MinPrice {
+		return false
+	}
+	if filter.MaxPrice != nil && p.Price.Float64() > *filter.MaxPrice {
+		return false
+	}
+	if filter.InStock && p.Quantity <= 0 {
+		return false
+	}
+	return true
+}
+
+// sortProducts applies the order of a filter on top of the id order
+func sortProducts(products []types.Product, order []db.OrderByClause) {
+	sort.SliceStable(products, func(i, j int) bool {
+		for _, clause := range order {
+			c := compareProducts(products[i], products[j], clause.Column)
+			if c == 0 {
+				continue
+			}
+			if clause.Desc {
+				return c > 0
+			}
+			return c < 0
+		}
+		return false
+	})
+}
+
+func compareProducts(a, b types.Product, column string) int {
+	switch column {
+	case "name":
+		return strings.Compare(a.Name, b.Name)
+	case "price":
+		return cmp.Compare(a.Price, b.Price)
+	case "quantity":
+		return cmp.Compare(a.Quantity, b.Quantity)
+	case "createdAt":
+		return a.CreatedAt.Compare(b.CreatedAt)
+	default:
+		return cmp.Compare(a.ID, b.ID)
+	}
+}
+
+// sorted returns the products ordered by id, like the SQL store
+func (s *ProductStore) sorted() []types.Product {
+	products := make([]types.Product, 0, len(s.products))
+	for _, p := range s.products {
+		products = append(products, p)
+	}
+
+	sort.Slice(products, func(i, j int) bool { return products[i].ID < products[j].ID })
+
+	return products
+}
+
+// RefreshTokenStore is an in-memory types.RefreshTokenStore with the rotation
+// rules of the SQL store: a token works once, and presenting it again revokes
+// every token of its user
+type RefreshTokenStore struct {
+	mu     sync.Mutex
+	nextID int
+	tokens map[string]*refreshToken
+}
+
+type refreshToken struct {
+	userID    int
+	expiresAt time.Time
+	revoked   bool
+}
+
+func NewRefreshTokenStore() *RefreshTokenStore {
+	return &RefreshTokenStore{nextID: 1, tokens: map[string]*refreshToken{}}
+}
+
+func (s *RefreshTokenStore) CreateRefreshToken(userID int) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	token := fmt.Sprintf("refresh-token-%d", s.nextID)
+	s.nextID++
+
+	expiration := time.Second * time.Duration(config.Envs.RefreshTokenExpirationInSeconds)
+	s.tokens[token] = &refreshToken{userID: userID, expiresAt: time.Now().Add(expiration)}
+
+	return token, nil
+}
+
+func (s *RefreshTokenStore) ConsumeRefreshToken(token string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, ok := s.tokens[token]
+	if !ok {
+		return 0, types.ErrInvalidRefreshToken
+	}
+
+	if t.revoked {
+		s.revoke(t.userID)
+		return 0, types.ErrRefreshTokenReused
+	}
+
+	if time.Now().After(t.expiresAt) {
+		return 0, types.ErrInvalidRefreshToken
+	}
+
+	t.revoked = true
+	return t.userID, nil
+}
+
+func (s *RefreshTokenStore) RevokeUserRefreshTokens(userID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.revoke(userID)
+	return nil
+}
+
+func (s *RefreshTokenStore) revoke(userID int) {
+	for _, t := range s.tokens {
+		if t.userID == userID {
+			t.revoked = true
+		}
+	}
+}
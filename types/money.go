@@ -0,0 +1,137 @@
+package types
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidMoney is returned for amounts that aren't a number with at most
+// two decimals
+var ErrInvalidMoney = errors.New("amount must be a number with at most two decimals")
+
+// Money is an amount in cents. Prices kept as float64 pick up rounding errors
+// (0.1 + 0.2 != 0.3), so amounts are parsed from their decimal text straight
+// into cents and never go through a float. It reads and writes JSON as a
+// number ("49.99" as a string is accepted too) and binds to DECIMAL(10, 2)
+// columns. The sign is left to validation (gt=0, gte=0), so a negative amount
+// is reported as an error of its field.
+type Money int64
+
+// ParseMoney parses a decimal amount such as "49.99", "5", "0.5" or "-3.25"
+func ParseMoney(s string) (Money, error) {
+	s, negative := strings.CutPrefix(s, "-")
+
+	units, fraction, hasFraction := strings.Cut(s, ".")
+	if units == "" || !isDigits(units) || (hasFraction && (len(fraction) == 0 || len(fraction) > 2 || !isDigits(fraction))) {
+		return 0, ErrInvalidMoney
+	}
+
+	whole, err := strconv.ParseInt(units, 10, 64)
+	if err != nil || whole > math.MaxInt64/100 {
+		return 0, ErrInvalidMoney
+	}
+
+	cents := whole * 100
+	if fraction != "" {
+		fractionCents, _ := strconv.ParseInt((fraction + "0")[:2], 10, 64)
+		cents += fractionCents
+	}
+
+	if negative {
+		cents = -cents
+	}
+
+	return Money(cents), nil
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+
+	return true
+}
+
+// String formats the amount with two decimals, e.g. "49.99"
+func (m Money) String() string {
+	sign := ""
+	cents := int64(m)
+	if cents < 0 {
+		sign, cents = "-", -cents
+	}
+
+	return fmt.Sprintf("%s%d.%02d", sign, cents/100, cents%100)
+}
+
+// Float64 is the amount in units, for comparisons and display only
+func (m Money) Float64() float64 {
+	return float64(m) / 100
+}
+
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+func (m *Money) UnmarshalJSON(data []byte) error {
+	s := string(data)
+	if unquoted, err := strconv.Unquote(s); err == nil {
+		s = unquoted
+	}
+
+	parsed, err := ParseMoney(s)
+	if err != nil {
+		return fmt.Errorf("invalid amount %s: %w", data, err)
+	}
+
+	*m = parsed
+	return nil
+}
+
+// Value binds the amount as decimal text, which DECIMAL columns store exactly
+func (m Money) Value() (driver.Value, error) {
+	return m.String(), nil
+}
+
+// Scan reads a DECIMAL column, which the MySQL driver returns as text, as
+// well as the integers and floats other drivers use. NULL scans as zero.
+func (m *Money) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*m = 0
+	case []byte:
+		return m.scanText(string(v))
+	case string:
+		return m.scanText(v)
+	case int64:
+		*m = Money(v * 100)
+	case float64:
+		*m = Money(math.Round(v * 100))
+	default:
+		return fmt.Errorf("cannot scan %T into Money", src)
+	}
+
+	return nil
+}
+
+// scanText parses stored text, which unlike client input may carry more
+// decimals when it comes from an aggregate
+func (m *Money) scanText(s string) error {
+	if parsed, err := ParseMoney(s); err == nil {
+		*m = parsed
+		return nil
+	}
+
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return fmt.Errorf("cannot scan %q into Money: %w", s, err)
+	}
+
+	*m = Money(math.Round(f * 100))
+	return nil
+}
@@ -0,0 +1,82 @@
+package types
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestMoney(t *testing.T) {
+	t.Run("should parse amounts into cents", func(t *testing.T) {
+		tests := []struct {
+			input    string
+			expected Money
+		}{
+			{`49.99`, 4999},
+			{`"49.99"`, 4999},
+			{`5`, 500},
+			{`0.5`, 50},
+			{`0.1`, 10},
+			{`-1.5`, -150},
+		}
+
+		for _, tt := range tests {
+			var m Money
+			if err := json.Unmarshal([]byte(tt.input), &m); err != nil {
+				t.Errorf("%s: unexpected error %v", tt.input, err)
+				continue
+			}
+
+			if m != tt.expected {
+				t.Errorf("%s: expected %d cents, got %d", tt.input, tt.expected, m)
+			}
+		}
+	})
+
+	t.Run("should reject malformed or too precise amounts", func(t *testing.T) {
+		for _, input := range []string{`1.999`, `1e3`, `"abc"`, `""`, `1.`, `-`, `--1`} {
+			var m Money
+			if err := json.Unmarshal([]byte(input), &m); err == nil {
+				t.Errorf("%s: expected an error, got %d", input, m)
+			}
+		}
+	})
+
+	t.Run("should write JSON as a number with two decimals", func(t *testing.T) {
+		marshalled, err := json.Marshal(struct {
+			Price Money `json:"price"`
+		}{Price: 4950})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if string(marshalled) != `{"price":49.50}` {
+			t.Errorf("unexpected JSON %s", marshalled)
+		}
+	})
+
+	t.Run("should scan decimal columns", func(t *testing.T) {
+		tests := []struct {
+			src      interface{}
+			expected Money
+		}{
+			{[]byte("199.00"), 19900},
+			{"-5.25", -525},
+			{[]byte("33.3333"), 3333},
+			{int64(7), 700},
+			{float64(0.29), 29},
+			{nil, 0},
+		}
+
+		for _, tt := range tests {
+			var m Money
+			if err := m.Scan(tt.src); err != nil {
+				t.Errorf("%v: unexpected error %v", tt.src, err)
+				continue
+			}
+
+			if m != tt.expected {
+				t.Errorf("%v: expected %d cents, got %d", tt.src, tt.expected, m)
+			}
+		}
+	})
+}
@@ -1,27 +1,85 @@
 package types
 
 import (
+	"errors"
 	"time"
+
+	"github.com/Jay1570/learning-go/db"
+)
+
+var (
+	ErrUserNotFound        = errors.New("user not found")
+	ErrInvalidRefreshToken = errors.New("invalid or expired refresh token")
+	ErrRefreshTokenReused  = errors.New("refresh token has already been used")
+	ErrUserHasOrders       = errors.New("user has orders and cannot be deleted")
+
+	ErrInvalidVerificationToken  = errors.New("invalid or expired verification token")
+	ErrInvalidPasswordResetToken = errors.New("invalid or expired password reset token")
+
+	ErrInvalidPriceRange = errors.New("minimum price is negative or greater than the maximum")
+	ErrInsufficientStock = errors.New("not enough stock left")
+	ErrDuplicateProduct  = errors.New("a product with this name already exists")
+)
+
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
 )
 
 type UserStore interface {
 	GetUserByEmail(email string) (*User, error)
 	GetUserByID(id int) (*User, error)
-	CreateUser(User) error
+	CreateUser(User) (int, error)
+	UpdateUser(id int, payload UpdateUserPayload) (*User, error)
+	UpdateUserPassword(id int, password string) error
+	MarkEmailVerified(id int) error
+	DeleteUser(id int) error
+	GetUsers(limit, offset int) (*db.CountResult[User], error)
+}
+
+type RefreshTokenStore interface {
+	CreateRefreshToken(userID int) (string, error)
+	ConsumeRefreshToken(token string) (int, error)
+	RevokeUserRefreshTokens(userID int) error
+}
+
+type VerificationTokenStore interface {
+	CreateVerificationToken(userID int) (string, error)
+	ConsumeVerificationToken(token string) (int, error)
+}
+
+type PasswordResetTokenStore interface {
+	CreatePasswordResetToken(userID int) (string, error)
+	// ResetPassword spends the token and sets the password of its owner
+	// together, returning the owner
+	ResetPassword(token, hashedPassword string) (int, error)
+}
+
+// EmailSender delivers transactional emails such as verification links
+type EmailSender interface {
+	SendEmail(to, subject, body string) error
 }
 
 type ProductStore interface {
 	GetProducts() ([]Product, error)
+	GetProductsPaginated(filter ProductFilter, limit, offset int) (*db.CountResult[Product], error)
+	GetProductByID(id int) (*Product, error)
+	GetLowStockProducts(threshold int) ([]Product, error)
+	GetProductStats() (*ProductStats, error)
 	CreateProduct(Product) error
+	UpdateProduct(id int, payload UpdateProductPayload) (*Product, error)
+	DeleteProduct(id int) error
 }
 
 type User struct {
-	ID        int       `json:"id" db:"id" insert:"-"`
-	FirstName string    `json:"firstName" db:"firstName" insert:"firstName"`
-	LastName  string    `json:"lastName" db:"lastName" insert:"lastName"`
-	Email     string    `json:"email" db:"email" insert:"email"`
-	Password  string    `json:"-" db:"password" insert:"password"`
-	CreatedAt time.Time `json:"createdAt" db:"createdAt" insert:"-"`
+	ID            int       `json:"id" db:"id" insert:"-"`
+	FirstName     string    `json:"firstName" db:"firstName" insert:"firstName"`
+	LastName      string    `json:"lastName" db:"lastName" insert:"lastName"`
+	Email         string    `json:"email" db:"email" insert:"email"`
+	Password      string    `json:"-" db:"password" insert:"password"`
+	Role          string    `json:"role" db:"role" insert:"-"`
+	EmailVerified bool      `json:"emailVerified" db:"emailVerified" insert:"-"`
+	CreatedAt     time.Time `json:"createdAt" db:"createdAt" insert:"-"`
 }
 
 type Product struct {
@@ -29,16 +87,70 @@ type Product struct {
 	Name        string    `json:"name" db:"name" insert:"name"`
 	Description string    `json:"description" db:"description" insert:"description"`
 	Image       string    `json:"image" db:"image" insert:"image"`
-	Price       float64   `json:"price" db:"price" insert:"price"`
+	Price       Money     `json:"price" db:"price" insert:"price"`
 	Quantity    int       `json:"quantity" db:"quantity" insert:"quantity"`
+	CreatedBy   *int      `json:"createdBy" db:"createdBy" insert:"createdBy"` // nil for products from before ownership
+	CategoryID  *int      `json:"categoryId" db:"categoryId" insert:"categoryId"`
 	CreatedAt   time.Time `json:"createdAt" db:"createdAt" insert:"-"`
 }
 
+type Category struct {
+	ID        int       `json:"id" db:"id" insert:"-"`
+	Name      string    `json:"name" db:"name" insert:"name"`
+	CreatedAt time.Time `json:"createdAt" db:"createdAt" insert:"-"`
+}
+
+// ProductWithCategory is a product joined with the name of its category,
+// nil when it has none
+type ProductWithCategory struct {
+	Product
+	CategoryName *string `json:"categoryName" db:"categoryName"`
+}
+
+// ProductStats summarizes the catalogue for the admin dashboard
+type ProductStats struct {
+	Count          int     `json:"count"`
+	InventoryValue Money   `json:"inventoryValue"` // sum of price * quantity
+	AveragePrice   float64 `json:"averagePrice"`
+	OutOfStock     int     `json:"outOfStock"`
+}
+
+// ProductFilter narrows a product listing; zero values don't filter
+type ProductFilter struct {
+	Query    string // Matched against name and description
+	MinPrice *float64
+	MaxPrice *float64
+	InStock  bool
+
+	// Order sorts the listing, by id when empty
+	Order []db.OrderByClause
+}
+
+type RefreshToken struct {
+	ID        int        `json:"id" db:"id" insert:"-"`
+	UserID    int        `json:"userId" db:"userId" insert:"userId"`
+	TokenHash string     `json:"-" db:"tokenHash" insert:"tokenHash"`
+	ExpiresAt time.Time  `json:"expiresAt" db:"expiresAt" insert:"expiresAt"`
+	RevokedAt *time.Time `json:"revokedAt" db:"revokedAt" insert:"revokedAt"`
+	CreatedAt time.Time  `json:"createdAt" db:"createdAt" insert:"-"`
+}
+
+// SingleUseToken is a row of the email verification and password reset
+// token tables
+type SingleUseToken struct {
+	ID        int        `json:"id" db:"id" insert:"-"`
+	UserID    int        `json:"userId" db:"userId" insert:"userId"`
+	TokenHash string     `json:"-" db:"tokenHash" insert:"tokenHash"`
+	ExpiresAt time.Time  `json:"expiresAt" db:"expiresAt" insert:"expiresAt"`
+	UsedAt    *time.Time `json:"usedAt" db:"usedAt" insert:"usedAt"`
+	CreatedAt time.Time  `json:"createdAt" db:"createdAt" insert:"-"`
+}
+
 type RegisterUserPayload struct {
 	FirstName string `json:"firstName" validate:"required"`
 	LastName  string `json:"lastName" validate:"required"`
 	Email     string `json:"email" validate:"required,email"`
-	Password  string `json:"password" validate:"required,min=3,max=130"`
+	Password  string `json:"password" validate:"required,strongpassword,max=130"`
 }
 
 type LoginUserPayload struct {
@@ -46,10 +158,48 @@ type LoginUserPayload struct {
 	Password string `json:"password" validate:"required"`
 }
 
+type UpdateUserPayload struct {
+	FirstName string `json:"firstName" db:"firstName"`
+	LastName  string `json:"lastName" db:"lastName"`
+	Email     string `json:"email" db:"email" validate:"omitempty,email"`
+}
+
+type ForgotPasswordPayload struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+type ResetPasswordPayload struct {
+	Token       string `json:"token" validate:"required"`
+	NewPassword string `json:"newPassword" validate:"required,strongpassword,max=130"`
+}
+
+type ChangePasswordPayload struct {
+	CurrentPassword string `json:"currentPassword" validate:"required"`
+	NewPassword     string `json:"newPassword" validate:"required,strongpassword,max=130"`
+}
+
+// DeleteAccountPayload confirms an account deletion with the current password
+type DeleteAccountPayload struct {
+	Password string `json:"password" validate:"required"`
+}
+
+type RefreshTokenPayload struct {
+	RefreshToken string `json:"refreshToken" validate:"required"`
+}
+
 type CreateProductPayload struct {
-	Name        string  `json:"name" validate:"required"`
-	Description string  `json:"description"`
-	Image       string  `json:"image"`
-	Price       float64 `json:"price" validate:"required"`
-	Quantity    int     `json:"quantity" validate:"required"`
+	Name        string `json:"name" validate:"required"`
+	Description string `json:"description"`
+	Image       string `json:"image"`
+	Price       Money  `json:"price" validate:"gt=0"`
+	Quantity    int    `json:"quantity" validate:"gte=0"`
+	CategoryID  *int   `json:"categoryId"`
+}
+
+type UpdateProductPayload struct {
+	Name        *string `json:"name" db:"name" validate:"omitempty,min=1"`
+	Description *string `json:"description" db:"description"`
+	Image       *string `json:"image" db:"image"`
+	Price       *Money  `json:"price" db:"price" validate:"omitempty,gt=0"`
+	Quantity    *int    `json:"quantity" db:"quantity" validate:"omitempty,gte=0"`
 }
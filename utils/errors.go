@@ -0,0 +1,113 @@
+package utils
+
+import (
+	"errors"
+	"net/http"
+)
+
+// Machine-readable error codes sent in the error envelope, so clients can
+// branch on them instead of parsing messages
+const (
+	CodeBadRequest       = "BAD_REQUEST"
+	CodeValidationFailed = "VALIDATION_FAILED"
+	CodeUnauthorized     = "UNAUTHORIZED"
+	CodeForbidden        = "FORBIDDEN"
+	CodeNotFound         = "NOT_FOUND"
+	CodeConflict         = "CONFLICT"
+	CodeUnprocessable    = "UNPROCESSABLE_ENTITY"
+	CodePayloadTooLarge  = "PAYLOAD_TOO_LARGE"
+	CodeTooManyRequests  = "TOO_MANY_REQUESTS"
+	CodeInternal         = "INTERNAL_ERROR"
+)
+
+// APIError is an error that knows how it is reported to clients
+type APIError struct {
+	Status  int    `json:"-"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Details any    `json:"details,omitempty"`
+}
+
+func (e *APIError) Error() string {
+	return e.Message
+}
+
+func NewAPIError(status int, code, message string) *APIError {
+	return &APIError{Status: status, Code: code, Message: message}
+}
+
+func BadRequest(message string) *APIError {
+	return NewAPIError(http.StatusBadRequest, CodeBadRequest, message)
+}
+
+func ValidationFailed(fields map[string]string) *APIError {
+	err := NewAPIError(http.StatusBadRequest, CodeValidationFailed, "invalid payload")
+	err.Details = fields
+	return err
+}
+
+func Unauthorized(message string) *APIError {
+	return NewAPIError(http.StatusUnauthorized, CodeUnauthorized, message)
+}
+
+func Forbidden(message string) *APIError {
+	return NewAPIError(http.StatusForbidden, CodeForbidden, message)
+}
+
+func NotFound(message string) *APIError {
+	return NewAPIError(http.StatusNotFound, CodeNotFound, message)
+}
+
+func Conflict(message string) *APIError {
+	return NewAPIError(http.StatusConflict, CodeConflict, message)
+}
+
+func UnprocessableEntity(message string) *APIError {
+	return NewAPIError(http.StatusUnprocessableEntity, CodeUnprocessable, message)
+}
+
+func PayloadTooLarge(message string) *APIError {
+	return NewAPIError(http.StatusRequestEntityTooLarge, CodePayloadTooLarge, message)
+}
+
+func Internal(message string) *APIError {
+	return NewAPIError(http.StatusInternalServerError, CodeInternal, message)
+}
+
+// codeForStatus is the code used for plain errors written with a status
+func codeForStatus(status int) string {
+	switch status {
+	case http.StatusBadRequest:
+		return CodeBadRequest
+	case http.StatusUnauthorized:
+		return CodeUnauthorized
+	case http.StatusForbidden:
+		return CodeForbidden
+	case http.StatusNotFound:
+		return CodeNotFound
+	case http.StatusConflict:
+		return CodeConflict
+	case http.StatusUnprocessableEntity:
+		return CodeUnprocessable
+	case http.StatusRequestEntityTooLarge:
+		return CodePayloadTooLarge
+	case http.StatusTooManyRequests:
+		return CodeTooManyRequests
+	case http.StatusInternalServerError:
+		return CodeInternal
+	default:
+		return http.StatusText(status)
+	}
+}
+
+// WriteAPIError writes err in the error envelope. Errors that aren't an
+// APIError are reported as internal errors.
+func WriteAPIError(w http.ResponseWriter, err error) {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	WriteJSON(w, apiErr.Status, map[string]any{"error": apiErr})
+}
@@ -0,0 +1,37 @@
+package utils
+
+import "github.com/Jay1570/learning-go/db"
+
+// PaginatedResponse is the envelope of every paginated listing
+type PaginatedResponse[T any] struct {
+	Data       []T  `json:"data"`
+	Page       int  `json:"page"`
+	PageSize   int  `json:"pageSize"`
+	TotalCount int  `json:"totalCount"`
+	TotalPages int  `json:"totalPages"`
+	HasNext    bool `json:"hasNext"`
+	HasPrev    bool `json:"hasPrev"`
+}
+
+// Paginate wraps one page of a count query in a PaginatedResponse. page is
+// 1-based; a pageSize that isn't positive means a single page holding
+// everything.
+func Paginate[T any](result *db.CountResult[T], page, pageSize int) PaginatedResponse[T] {
+	totalPages := 0
+	switch {
+	case pageSize > 0:
+		totalPages = (result.Count + pageSize - 1) / pageSize
+	case result.Count > 0:
+		totalPages = 1
+	}
+
+	return PaginatedResponse[T]{
+		Data:       result.Data,
+		Page:       page,
+		PageSize:   pageSize,
+		TotalCount: result.Count,
+		TotalPages: totalPages,
+		HasNext:    page < totalPages,
+		HasPrev:    page > 1,
+	}
+}
@@ -0,0 +1,32 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/Jay1570/learning-go/db"
+)
+
+func TestPaginate(t *testing.T) {
+	for _, tt := range []struct {
+		name       string
+		count      int
+		page       int
+		pageSize   int
+		totalPages int
+		hasNext    bool
+		hasPrev    bool
+	}{
+		{"no results", 0, 1, 10, 0, false, false},
+		{"an exact multiple", 20, 1, 10, 2, true, false},
+		{"a partial last page", 25, 3, 10, 3, false, true},
+		{"a page size of zero", 25, 1, 0, 1, false, false},
+	} {
+		t.Run("should paginate "+tt.name, func(t *testing.T) {
+			response := Paginate(&db.CountResult[int]{Count: tt.count}, tt.page, tt.pageSize)
+
+			if response.TotalPages != tt.totalPages || response.HasNext != tt.hasNext || response.HasPrev != tt.hasPrev {
+				t.Errorf("expected %d pages, next %t and prev %t, got %+v", tt.totalPages, tt.hasNext, tt.hasPrev, response)
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package utils
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+)
+
+// QueryInt reads an integer query param, returning fallback when it is
+// absent. Malformed values and values outside [min, max] are a 400.
+func QueryInt(r *http.Request, key string, fallback, min, max int) (int, error) {
+	value := r.URL.Query().Get(key)
+	if value == "" {
+		return fallback, nil
+	}
+
+	i, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, BadRequest(fmt.Sprintf("%s must be an integer", key))
+	}
+
+	if i < min || i > max {
+		return 0, BadRequest(fmt.Sprintf("%s must be between %d and %d", key, min, max))
+	}
+
+	return i, nil
+}
+
+// QueryFloat reads a number query param, returning fallback when it is
+// absent. Malformed values, NaN, infinities and values outside [min, max] are
+// a 400.
+func QueryFloat(r *http.Request, key string, fallback, min, max float64) (float64, error) {
+	value := r.URL.Query().Get(key)
+	if value == "" {
+		return fallback, nil
+	}
+
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+		return 0, BadRequest(fmt.Sprintf("%s must be a number", key))
+	}
+
+	if f < min || f > max {
+		return 0, BadRequest(fmt.Sprintf("%s must be between %g and %g", key, min, max))
+	}
+
+	return f, nil
+}
+
+// QueryBool reads a boolean query param ("true", "false", "1", "0", ...),
+// returning fallback when it is absent. Malformed values are a 400.
+func QueryBool(r *http.Request, key string, fallback bool) (bool, error) {
+	value := r.URL.Query().Get(key)
+	if value == "" {
+		return fallback, nil
+	}
+
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, BadRequest(fmt.Sprintf("%s must be true or false", key))
+	}
+
+	return b, nil
+}
@@ -0,0 +1,36 @@
+package utils
+
+import (
+	"math"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestQueryFloat(t *testing.T) {
+	for _, tt := range []struct {
+		value    string
+		expected float64
+		valid    bool
+	}{
+		{"", 1, true},
+		{"2.5", 2.5, true},
+		{"cheap", 0, false},
+		{"-1", 0, false},
+		{"NaN", 0, false},
+		{"Inf", 0, false},
+		{"-Inf", 0, false},
+	} {
+		t.Run("should read "+tt.value, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/products?price="+url.QueryEscape(tt.value), nil)
+
+			f, err := QueryFloat(r, "price", 1, 0, math.MaxFloat64)
+			if tt.valid && (err != nil || f != tt.expected) {
+				t.Errorf("expected %g, got %g (%v)", tt.expected, f, err)
+			}
+			if !tt.valid && err == nil {
+				t.Errorf("expected an error, got %g", f)
+			}
+		})
+	}
+}
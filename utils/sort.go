@@ -0,0 +1,44 @@
+package utils
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/Jay1570/learning-go/db"
+)
+
+// ParseSort reads the sort query param, a comma separated list of columns
+// where a leading "-" sorts descending (e.g. "price,-createdAt").
+//
+// Every list endpoint passes its own allowlist of sortable columns; anything
+// else is rejected with a 400, so client input never reaches ORDER BY
+// unchecked. A new endpoint declares its allowlist next to its handler and
+// passes it here. An absent param returns fallback.
+func ParseSort(r *http.Request, allowed []string, fallback ...db.OrderByClause) ([]db.OrderByClause, error) {
+	value := strings.TrimSpace(r.URL.Query().Get("sort"))
+	if value == "" {
+		return fallback, nil
+	}
+
+	var order []db.OrderByClause
+	for _, field := range strings.Split(value, ",") {
+		field = strings.TrimSpace(field)
+
+		desc := strings.HasPrefix(field, "-")
+		column := strings.TrimPrefix(field, "-")
+
+		if !slices.Contains(allowed, column) {
+			return nil, BadRequest(fmt.Sprintf("cannot sort by %q, allowed: %s", column, strings.Join(allowed, ", ")))
+		}
+
+		if desc {
+			order = append(order, db.Desc(column))
+		} else {
+			order = append(order, db.Asc(column))
+		}
+	}
+
+	return order, nil
+}
@@ -2,20 +2,59 @@ package utils
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"strings"
+	"time"
 
-	"github.com/go-playground/validator/v10"
+	"github.com/Jay1570/learning-go/config"
 )
 
-var Validate = validator.New()
+// AuthCookieName is the httpOnly cookie carrying the access token in cookie mode
+const AuthCookieName = "auth_token"
 
+// ParseJSON decodes the request body into payload. Bodies over
+// config.Envs.MaxRequestBodyBytes are rejected with a 413, and unknown fields
+// are rejected when config.Envs.DisallowUnknownJSONFields is set. Errors are
+// APIErrors, ready for WriteAPIError.
 func ParseJSON(r *http.Request, payload any) error {
-	if r.Body == nil {
-		return fmt.Errorf("Missing Request Body")
+	if r.Body == nil || r.Body == http.NoBody {
+		return BadRequest("Missing Request Body")
 	}
 
-	return json.NewDecoder(r.Body).Decode(payload)
+	body := http.MaxBytesReader(nil, r.Body, config.Envs.MaxRequestBodyBytes)
+	decoder := json.NewDecoder(body)
+	if config.Envs.DisallowUnknownJSONFields {
+		decoder.DisallowUnknownFields()
+	}
+
+	if err := decoder.Decode(payload); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			return PayloadTooLarge(fmt.Sprintf("request body must not exceed %d bytes", maxBytesErr.Limit))
+		}
+		return BadRequest(err.Error())
+	}
+
+	return nil
+}
+
+// VersionPrefix is the path an API version is mounted under, e.g. "/api/v1"
+func VersionPrefix(version string) string {
+	return config.Envs.APIBasePath + "/" + version
+}
+
+// APIURL is the public URL of a path on an API version, for links sent out of
+// band such as in emails
+func APIURL(version, path string) string {
+	return fmt.Sprintf("%s:%s%s%s", config.Envs.PublicHost, config.Envs.Port, VersionPrefix(version), path)
+}
+
+// NormalizeEmail trims and lowercases an email address so the same mailbox
+// always maps to the same account
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
 }
 
 func WriteJSON(w http.ResponseWriter, status int, v any) error {
@@ -25,8 +64,18 @@ func WriteJSON(w http.ResponseWriter, status int, v any) error {
 	return json.NewEncoder(w).Encode(v)
 }
 
+// WriteError writes err as {"error":{"code":...,"message":...}}. An APIError
+// keeps its own code, other errors get the code of the status.
 func WriteError(w http.ResponseWriter, status int, err error) {
-	WriteJSON(w, status, map[string]string{"error": err.Error()})
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		WriteJSON(w, status, map[string]any{"error": apiErr})
+		return
+	}
+
+	WriteJSON(w, status, map[string]any{
+		"error": NewAPIError(status, codeForStatus(status), err.Error()),
+	})
 }
 
 func GetTokenFromRequest(r *http.Request) string {
@@ -41,5 +90,25 @@ func GetTokenFromRequest(r *http.Request) string {
 		return tokenQuery
 	}
 
+	if config.Envs.AuthCookieEnabled {
+		if cookie, err := r.Cookie(AuthCookieName); err == nil {
+			return cookie.Value
+		}
+	}
+
 	return ""
 }
+
+// SetAuthCookie stores the access token in an httpOnly cookie so browser
+// clients never have to handle it from JS
+func SetAuthCookie(w http.ResponseWriter, token string, expiration time.Duration) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     AuthCookieName,
+		Value:    token,
+		Path:     "/",
+		MaxAge:   int(expiration.Seconds()),
+		HttpOnly: true,
+		Secure:   config.Envs.AuthCookieSecure,
+		SameSite: http.SameSiteStrictMode,
+	})
+}
@@ -0,0 +1,134 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"unicode"
+
+	"github.com/Jay1570/learning-go/config"
+	"github.com/go-playground/validator/v10"
+)
+
+var Validate = newValidator()
+
+func newValidator() *validator.Validate {
+	v := validator.New()
+
+	// Report fields by their JSON name so errors match what clients sent
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name := strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+		if name == "-" {
+			return ""
+		}
+		if name == "" {
+			return field.Name
+		}
+		return name
+	})
+
+	v.RegisterValidation("strongpassword", validateStrongPassword)
+
+	return v
+}
+
+// validateStrongPassword enforces the password policy from config: a minimum
+// length and a minimum number of character classes (lower, upper, digit, symbol)
+func validateStrongPassword(fl validator.FieldLevel) bool {
+	password := fl.Field().String()
+
+	if int64(len([]rune(password))) < config.Envs.PasswordMinLength {
+		return false
+	}
+
+	var lower, upper, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			symbol = true
+		}
+	}
+
+	classes := 0
+	for _, present := range []bool{lower, upper, digit, symbol} {
+		if present {
+			classes++
+		}
+	}
+
+	return int64(classes) >= config.Envs.PasswordRequiredClasses
+}
+
+// ValidationErrors converts validator errors into a field -> message map
+func ValidationErrors(err error) map[string]string {
+	fields := map[string]string{}
+
+	var validationErrors validator.ValidationErrors
+	if !errors.As(err, &validationErrors) {
+		return fields
+	}
+
+	for _, fe := range validationErrors {
+		fields[fe.Field()] = validationMessage(fe)
+	}
+
+	return fields
+}
+
+func WriteValidationError(w http.ResponseWriter, err error) {
+	var validationErrors validator.ValidationErrors
+	if !errors.As(err, &validationErrors) {
+		WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	WriteFieldErrors(w, ValidationErrors(err))
+}
+
+// WriteFieldErrors writes a 400 with per-field messages, for checks that run
+// outside the validator (e.g. ones that need the database)
+func WriteFieldErrors(w http.ResponseWriter, fields map[string]string) {
+	WriteAPIError(w, ValidationFailed(fields))
+}
+
+func validationMessage(fe validator.FieldError) string {
+	isString := fe.Kind() == reflect.String
+
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "email":
+		return "must be a valid email"
+	case "strongpassword":
+		return fmt.Sprintf("must be at least %d characters long and contain %d of: lowercase letters, uppercase letters, digits, symbols",
+			config.Envs.PasswordMinLength, config.Envs.PasswordRequiredClasses)
+	case "min":
+		if isString {
+			return fmt.Sprintf("must be at least %s characters long", fe.Param())
+		}
+		return fmt.Sprintf("must be at least %s", fe.Param())
+	case "max":
+		if isString {
+			return fmt.Sprintf("must be at most %s characters long", fe.Param())
+		}
+		return fmt.Sprintf("must be at most %s", fe.Param())
+	case "gt":
+		return fmt.Sprintf("must be greater than %s", fe.Param())
+	case "gte":
+		return fmt.Sprintf("must be greater than or equal to %s", fe.Param())
+	case "lt":
+		return fmt.Sprintf("must be less than %s", fe.Param())
+	case "lte":
+		return fmt.Sprintf("must be less than or equal to %s", fe.Param())
+	default:
+		return fmt.Sprintf("failed the %s validation", fe.Tag())
+	}
+}